@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"typemon/internal/generator"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneMaxAge time.Duration
+	pruneAll    bool
+)
+
+// Команда cache
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the render cache",
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove cached renders that were not used recently",
+	RunE:  runCachePrune,
+}
+
+func init() {
+	cachePruneCmd.Flags().DurationVar(&pruneMaxAge, "max-age", 7*24*time.Hour, "Remove entries not used for longer than this")
+	cachePruneCmd.Flags().BoolVar(&pruneAll, "all", false, "Remove every cached render")
+	cacheCmd.AddCommand(cachePruneCmd)
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	maxAge := pruneMaxAge
+	if pruneAll {
+		maxAge = 0
+	}
+	removed, freed, err := generator.PruneRenderCache(maxAge)
+	if err != nil {
+		return errors.Join(errors.New("failed to prune render cache"), err)
+	}
+	fmt.Printf("removed %d cached render(s), freed %.1f MiB\n", removed, float64(freed)/(1<<20))
+	return nil
+}
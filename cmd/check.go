@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+
+	"github.com/spf13/cobra"
+)
+
+// Команда check
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the computed layout for keycap collisions and parts cutting into keys",
+	RunE:  runCheck,
+}
+
+func init() {
+	checkCmd.Flags().BoolVar(&strictMode, "strict", false, "Exit with an error when keycaps collide or parts cut into keys")
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	generator, warnings, err := generator.New(configName, generatorOptions())
+	if err != nil {
+		return errors.Join(errors.New("failed to create generator"), err)
+	}
+	printWarnings(cmd.ErrOrStderr(), warnings)
+	problems := 0
+	for _, half := range config.Halves {
+		collisions, err := generator.Collisions(half)
+		if err != nil {
+			return errors.Join(errors.New("failed to check "+half+" half"), err)
+		}
+		interferences, err := generator.Interferences(half)
+		if err != nil {
+			return errors.Join(errors.New("failed to check "+half+" half"), err)
+		}
+		for _, collision := range collisions {
+			fmt.Println(half + " half: " + collision.String())
+		}
+		for _, interference := range interferences {
+			fmt.Println(half + " half: " + interference.String())
+		}
+		fmt.Printf("%s half: %d collision(s), %d interference(s)\n", half, len(collisions), len(interferences))
+		problems += len(collisions) + len(interferences)
+	}
+	if strictMode && problems > 0 {
+		return errors.New("layout check failed")
+	}
+	return nil
+}
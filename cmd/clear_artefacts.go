@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"typemon/internal/generator"
 
@@ -32,7 +33,7 @@ func runClearArtefacts(cmd *cobra.Command, args []string) error {
 			}
 		}
 	}
-	// delete all *.g.step files in the models directory
+	// delete all rendered *.g.stl, *.g.3mf and *.g.off files in the models directory
 	modelsDir := generator.RenderDir
 	files, err = os.ReadDir(modelsDir)
 	if err != nil && os.IsNotExist(err) {
@@ -42,7 +43,9 @@ func runClearArtefacts(cmd *cobra.Command, args []string) error {
 		return errors.Join(errors.New("read models directory"), err)
 	}
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), generator.GeneratedRenderExtension()) {
+		if slices.ContainsFunc(generator.GeneratedRenderExtensions(), func(ext string) bool {
+			return strings.HasSuffix(file.Name(), ext)
+		}) {
 			err = os.Remove(filepath.Join(modelsDir, file.Name()))
 			if err != nil {
 				return errors.Join(errors.New("remove model file"), err)
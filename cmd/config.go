@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	showResolved bool
+	showHalf     string
+)
+
+// Команда config
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect keyboard configs",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the config, optionally with all defaults applied",
+	RunE:  runConfigShow,
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&showResolved, "resolved", false, "Print the fully resolved config the generator uses")
+	configShowCmd.Flags().StringVar(&showHalf, "half", "", "With --resolved, print the config of the left or right half with its overrides applied")
+	configCmd.AddCommand(configShowCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	path := generator.ConfigPath(configName)
+	if !showResolved {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Join(errors.New("failed to read config"), err)
+		}
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	}
+	doc, err := config.LoadDocument(path)
+	if err != nil {
+		return errors.Join(errors.New("failed to load config"), err)
+	}
+	if showHalf != "" {
+		doc, err = doc.Half(showHalf)
+		if err != nil {
+			return errors.Join(errors.New("failed to resolve half"), err)
+		}
+	}
+	cfg := doc.Config
+	encoder := yaml.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	err = encoder.Encode(cfg)
+	if err != nil {
+		return errors.Join(errors.New("failed to encode config"), err)
+	}
+	return nil
+}
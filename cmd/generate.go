@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"typemon/internal/config"
 	"typemon/internal/generator"
+	"typemon/internal/watch"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 )
 
 var (
-	watchMode bool
+	watchMode     bool
+	watchRender   bool
+	renderProfile string
+	strictMode    bool
 )
 
 // Команда generate
@@ -22,45 +31,106 @@ var genCmd = &cobra.Command{
 
 func init() {
 	genCmd.Flags().BoolVarP(&watchMode, "watch", "w", false, "Watch for changes and regenerate in real time")
+	genCmd.Flags().StringVarP(&renderProfile, "profile", "p", "", "Render profile, overrides render.profile from the config")
+	genCmd.Flags().BoolVar(&strictMode, "strict", false, "Fail on keycap collisions and parts cutting into keys instead of warning")
+	genCmd.Flags().BoolVar(&watchRender, "render", false, "Render the changed halves with OpenSCAD after every regeneration, needs --watch")
+	genCmd.Flags().StringVarP(&renderFormat, "format", "f", generator.DefaultRenderFormat, "Output format of --render: stl, 3mf or off")
+	addRenderFlags(genCmd)
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
-	if watchMode {
-		watcher, err := fsnotify.NewWatcher()
+	if !watchMode {
+		if watchRender {
+			return errors.New("--render needs --watch, use the render command for a single render")
+		}
+		return runGenerator(cmd.Context(), cmd.ErrOrStderr(), nil)
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watcher, err := watch.New(watch.DefaultDebounce)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	var renderer *generator.WatchRenderer
+	if watchRender {
+		renderer = generator.NewWatchRenderer(renderOptions())
+		defer renderer.Stop()
+	}
+
+	regenerate := func() {
+		if renderer != nil {
+			// OpenSCAD must not read the files while they are rewritten
+			renderer.Stop()
+		}
+		err := runGenerator(ctx, cmd.ErrOrStderr(), renderer)
+		if err != nil {
+			fmt.Println(err.Error())
+		}
+		// extends may have changed, so the watched files are looked up again
+		err = watcher.Set(generator.Dependencies(configName))
 		if err != nil {
-			return errors.Join(errors.New("failed to create watcher"), err)
+			fmt.Println("failed to update watched files: " + err.Error())
 		}
-		defer watcher.Close()
-		watcher.Add(generator.ConfigPath(configName))
-		watcher.Add(generator.SwitchModulesConfigDir)
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return errors.New("watcher closed")
-				}
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					err = runGenerator()
-					if err != nil {
-						fmt.Println("failed to generate: " + err.Error())
-					}
-				}
-			}
+	}
+	regenerate()
+	fmt.Println("watching for changes, press Ctrl+C to stop")
+	err = watcher.Run(ctx, func(changed []string) {
+		for _, path := range changed {
+			fmt.Println("changed: " + path)
 		}
-	} else {
-		return runGenerator()
+		regenerate()
+	})
+	if err != nil {
+		return errors.Join(errors.New("failed to watch"), err)
+	}
+	if renderer != nil {
+		renderer.Stop()
 	}
+	fmt.Println("stopped watching")
+	return nil
 }
 
-func runGenerator() error {
-	generator, err := generator.New(configName)
+// runGenerator generates the model and, with a renderer, starts rendering it
+// in the background. Config and layout warnings go to stderr.
+func runGenerator(ctx context.Context, stderr io.Writer, renderer *generator.WatchRenderer) error {
+	generator, warnings, err := generator.New(configName, generatorOptions())
 	if err != nil {
 		return errors.Join(errors.New("failed to create generator"), err)
 	}
-	err = generator.Generate()
+	printWarnings(stderr, warnings)
+	problems, err := generator.Generate()
 	if err != nil {
 		return errors.Join(errors.New("failed to generate"), err)
 	}
+	printLayoutWarnings(stderr, problems)
 	fmt.Println("generated successfully")
+	if renderer == nil {
+		return nil
+	}
+	err = renderer.Start(ctx, generator)
+	if err != nil {
+		return errors.Join(errors.New("failed to start render"), err)
+	}
 	return nil
 }
+
+// printWarnings writes config warnings to w, stderr in the commands, so they
+// never mix with the command output.
+func printWarnings(w io.Writer, warnings config.Diagnostics) {
+	for _, warning := range warnings {
+		fmt.Fprintln(w, warning.String())
+	}
+}
+
+// printLayoutWarnings writes keycap collisions and parts cutting into keys
+// found while generating to w.
+func printLayoutWarnings(w io.Writer, problems []string) {
+	for _, problem := range problems {
+		fmt.Fprintln(w, "warning: "+problem)
+	}
+}
+
+func generatorOptions() generator.Options {
+	return generator.Options{Profile: renderProfile, Strict: strictMode}
+}
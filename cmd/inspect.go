@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"text/tabwriter"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+	"typemon/internal/geometry"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectHalf       string
+	inspectKeysFormat string
+)
+
+// Команда inspect
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Inspect the computed key layout",
+}
+
+var inspectHeightsCmd = &cobra.Command{
+	Use:   "heights",
+	Short: "Print the keywell surface height and tilt of every key",
+	RunE:  runInspectHeights,
+}
+
+var inspectKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Print the position, rotation and keycap corners of every key as JSON or CSV",
+	Long: "Print every keywell, extra and thumb key in desk coordinates, after M_base and with the right half mirrored. " +
+		"Both halves are printed unless --half is given. Rotations are Euler angles in degrees, keycap corners are " +
+		"the corners of the keycap top: top-left, top-right, bottom-left, bottom-right in key coordinates.",
+	RunE: runInspectKeys,
+}
+
+func init() {
+	inspectCmd.PersistentFlags().StringVar(&inspectHalf, "half", config.HalfLeft, "Keyboard half to inspect, left or right")
+	inspectKeysCmd.Flags().StringVarP(&inspectKeysFormat, "format", "f", "json", "Output format: json or csv")
+	inspectCmd.AddCommand(inspectHeightsCmd, inspectKeysCmd)
+}
+
+func runInspectHeights(cmd *cobra.Command, args []string) error {
+	gen, warnings, err := generator.New(configName, generatorOptions())
+	if err != nil {
+		return errors.Join(errors.New("failed to create generator"), err)
+	}
+	printWarnings(cmd.ErrOrStderr(), warnings)
+	layout, err := gen.Layout(inspectHalf)
+	if err != nil {
+		return errors.Join(errors.New("failed to compute layout"), err)
+	}
+	params := layout.Params
+
+	fmt.Fprintf(cmd.OutOrStdout(), "curvature profile: %s\n\n", gen.CurvatureProfile(inspectHalf))
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "row\t")
+	for col := range params.Columns {
+		fmt.Fprintf(w, "column %d\t", col)
+	}
+	fmt.Fprintln(w)
+	for row := range params.Rows {
+		fmt.Fprintf(w, "%d\t", row)
+		for col := range params.Columns {
+			if !params.HasKey(col, row) {
+				fmt.Fprint(w, "-\t")
+				continue
+			}
+			key := layout.Keys[col][row]
+			rotation := key.Euler()
+			fmt.Fprintf(w, "%.2f (%.1f°, %.1f°)\t", key.Translation()[2], rotation[0], rotation[1])
+		}
+		fmt.Fprintln(w)
+	}
+	for i, key := range layout.Extra {
+		rotation := key.Euler()
+		fmt.Fprintf(w, "extra %s\t%.2f (%.1f°, %.1f°)\t\n", strconv.Itoa(i), key.Translation()[2], rotation[0], rotation[1])
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "\nheight in mm on the keywell plane and tilt around X and Y, before the base tilt")
+	return nil
+}
+
+// inspectedKey is a key as printed by inspect keys. Column and row are set for
+// keywell keys, index for extra and thumb keys.
+type inspectedKey struct {
+	Half          string           `json:"half"`
+	Kind          string           `json:"kind"`
+	Column        *int             `json:"column,omitempty"`
+	Row           *int             `json:"row,omitempty"`
+	Index         *int             `json:"index,omitempty"`
+	SwitchType    string           `json:"switch_type"`
+	Position      geometry.Vec3    `json:"position"`
+	Rotation      geometry.Vec3    `json:"rotation"`
+	Normal        geometry.Vec3    `json:"normal"`
+	KeycapCorners [4]geometry.Vec3 `json:"keycap_corners"`
+}
+
+// roundMM drops floating point noise, a tenth of a micron is far below what
+// a printer resolves.
+func roundMM(v geometry.Vec3) geometry.Vec3 {
+	for i := range v {
+		v[i] = math.Round(v[i]*1e4) / 1e4
+		// avoid -0 in the output
+		if v[i] == 0 {
+			v[i] = 0
+		}
+	}
+	return v
+}
+
+func newInspectedKey(half string, key geometry.PlacedKey) inspectedKey {
+	k := inspectedKey{
+		Half:       half,
+		Kind:       "keywell",
+		SwitchType: key.SwitchType,
+		Position:   roundMM(key.Transform.Translation()),
+		Rotation:   roundMM(key.Transform.Euler()),
+		Normal:     roundMM(key.Normal()),
+	}
+	switch {
+	case key.Key.Thumb:
+		k.Kind = "thumb"
+		k.Index = &key.Key.Slot
+	case key.Key.Extra:
+		k.Kind = "extra"
+		k.Index = &key.Key.Slot
+	default:
+		k.Column = &key.Key.Column
+		k.Row = &key.Key.Row
+	}
+	for i, corner := range key.KeycapCorners() {
+		k.KeycapCorners[i] = roundMM(corner)
+	}
+	return k
+}
+
+func runInspectKeys(cmd *cobra.Command, args []string) error {
+	if inspectKeysFormat != "json" && inspectKeysFormat != "csv" {
+		return fmt.Errorf("unsupported format %q, expected json or csv", inspectKeysFormat)
+	}
+	gen, warnings, err := generator.New(configName, generatorOptions())
+	if err != nil {
+		return errors.Join(errors.New("failed to create generator"), err)
+	}
+	printWarnings(cmd.ErrOrStderr(), warnings)
+	halves := config.Halves
+	if cmd.Flags().Changed("half") {
+		halves = []string{inspectHalf}
+	}
+	keys := []inspectedKey{}
+	for _, half := range halves {
+		placed, err := gen.PlacedKeys(half)
+		if err != nil {
+			return errors.Join(errors.New("failed to compute layout"), err)
+		}
+		for _, key := range placed {
+			keys = append(keys, newInspectedKey(half, key))
+		}
+	}
+	if inspectKeysFormat == "csv" {
+		return writeKeysCSV(cmd.OutOrStdout(), keys)
+	}
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(keys)
+}
+
+func writeKeysCSV(out io.Writer, keys []inspectedKey) error {
+	header := []string{"half", "kind", "column", "row", "index", "switch_type", "x", "y", "z", "rx", "ry", "rz", "nx", "ny", "nz"}
+	for corner := range 4 {
+		for _, axis := range []string{"x", "y", "z"} {
+			header = append(header, fmt.Sprintf("corner%d_%s", corner, axis))
+		}
+	}
+	optional := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+	w := csv.NewWriter(out)
+	err := w.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		record := []string{key.Half, key.Kind, optional(key.Column), optional(key.Row), optional(key.Index), key.SwitchType}
+		vectors := append([]geometry.Vec3{key.Position, key.Rotation, key.Normal}, key.KeycapCorners[:]...)
+		for _, v := range vectors {
+			for _, value := range v {
+				record = append(record, strconv.FormatFloat(value, 'f', -1, 64))
+			}
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdirWithWarningConfig moves into a working directory whose configs/warn.yml
+// extends the default config with a row modifier outside the layout, which
+// validates with a warning.
+func chdirWithWarningConfig(t *testing.T) {
+	t.Helper()
+	repo, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = os.Mkdir(filepath.Join(dir, "configs"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// switch modules point into scad/modules
+	for _, name := range []string{"configs/switches", "configs/pcbs", "scad"} {
+		err = os.Symlink(filepath.Join(repo, name), filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := "extends: " + filepath.Join(repo, "configs", "default.yml") + "\n" +
+		"keywell:\n  modifiers:\n    rows:\n      7:\n        offset:\n          z: 1\n"
+	err = os.WriteFile(filepath.Join(dir, "configs", "warn.yml"), []byte(config), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+}
+
+// inspectKeys runs inspect keys and returns its stdout and stderr.
+func inspectKeys(t *testing.T, format string) (string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs([]string{"inspect", "keys", "-c", "warn", "-f", format})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	})
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "keywell.modifiers.rows.7") {
+		t.Errorf("stderr = %q, want the config warning", stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestInspectKeysCSVWithWarnings(t *testing.T) {
+	chdirWithWarningConfig(t)
+	stdout, _ := inspectKeys(t, "csv")
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not CSV: %v\n%s", err, stdout)
+	}
+	if len(records) < 2 || records[0][0] != "half" {
+		t.Fatalf("output starts with %q, want the header", records[0])
+	}
+}
+
+func TestInspectKeysJSONWithWarnings(t *testing.T) {
+	chdirWithWarningConfig(t)
+	stdout, _ := inspectKeys(t, "json")
+	var keys []inspectedKey
+	err := json.Unmarshal([]byte(stdout), &keys)
+	if err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, stdout)
+	}
+	if len(keys) == 0 {
+		t.Fatal("output has no keys")
+	}
+}
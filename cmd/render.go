@@ -1,14 +1,69 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"errors"
+	"os"
+	"time"
+	"typemon/internal/generator"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	openscadBinary string
+	renderTimeout  time.Duration
+	renderFormat   string
+	renderNoCache  bool
+)
 
 // Команда render
 var renderCmd = &cobra.Command{
 	Use:   "render",
-	Short: "Generate OpenSCAD model and STEP files (stub)",
+	Short: "Generate OpenSCAD model and export it with the OpenSCAD CLI",
 	RunE:  runRender,
 }
 
+func init() {
+	addRenderFlags(renderCmd)
+	renderCmd.Flags().StringVarP(&renderProfile, "profile", "p", "", "Render profile, overrides render.profile from the config")
+	renderCmd.Flags().StringVarP(&renderFormat, "format", "f", generator.DefaultRenderFormat, "Output format: stl, 3mf or off")
+}
+
+// addRenderFlags adds the OpenSCAD flags shared by render, generate --render
+// and serve.
+func addRenderFlags(cmd *cobra.Command) {
+	defaultBinary := os.Getenv("TYPEMON_OPENSCAD")
+	if defaultBinary == "" {
+		defaultBinary = generator.DefaultOpenSCAD
+	}
+	cmd.Flags().StringVar(&openscadBinary, "openscad", defaultBinary, "OpenSCAD binary name or path (env TYPEMON_OPENSCAD)")
+	cmd.Flags().DurationVar(&renderTimeout, "timeout", generator.DefaultRenderTimeout, "Timeout of a single OpenSCAD job, 0 disables it")
+	cmd.Flags().BoolVar(&renderNoCache, "no-cache", false, "Always run OpenSCAD, even when a cached render matches")
+}
+
 func runRender(cmd *cobra.Command, args []string) error {
+	generator, warnings, err := generator.New(configName, generatorOptions())
+	if err != nil {
+		return errors.Join(errors.New("failed to create generator"), err)
+	}
+	printWarnings(cmd.ErrOrStderr(), warnings)
+	problems, err := generator.Generate()
+	if err != nil {
+		return errors.Join(errors.New("failed to generate"), err)
+	}
+	printLayoutWarnings(cmd.ErrOrStderr(), problems)
+	err = generator.Render(cmd.Context(), renderOptions())
+	if err != nil {
+		return errors.Join(errors.New("failed to render"), err)
+	}
 	return nil
 }
+
+func renderOptions() generator.RenderOptions {
+	return generator.RenderOptions{
+		OpenSCAD: openscadBinary,
+		Timeout:  renderTimeout,
+		Format:   renderFormat,
+		NoCache:  renderNoCache,
+	}
+}
@@ -22,7 +22,7 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&configName, "config", "c", defaultConfigPath, "YAML config file name (without extension)")
 
-	rootCmd.AddCommand(genCmd, renderCmd, clearArtefactsCmd)
+	rootCmd.AddCommand(genCmd, renderCmd, validateCmd, checkCmd, inspectCmd, configCmd, schemaCmd, cacheCmd, clearArtefactsCmd, serveCmd)
 }
 
 func Execute() error {
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaOutput       string
+	schemaSwitchModule bool
+	schemaPCB          bool
+)
+
+// Команда schema
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Write JSON Schema for the config, switch module or pcb definition format",
+	RunE:  runSchema,
+}
+
+func init() {
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "Write the schema to a file instead of stdout")
+	schemaCmd.Flags().BoolVar(&schemaSwitchModule, "switch-module", false, "Write the schema of switch module definitions")
+	schemaCmd.Flags().BoolVar(&schemaPCB, "pcb", false, "Write the schema of pcb definitions")
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	var schema map[string]any
+	switch {
+	case schemaSwitchModule:
+		schema = config.SwitchModuleSchema()
+	case schemaPCB:
+		schema = config.PCBSchema()
+	default:
+		options := config.SchemaOptions{
+			RenderProfiles: slices.Collect(maps.Keys(config.BuiltinRenderProfiles)),
+		}
+		modules, err := config.LoadSwitchModules(generator.SwitchModulesConfigDir)
+		if err != nil {
+			return errors.Join(errors.New("failed to load switch modules"), err)
+		}
+		options.SwitchModules = slices.Collect(maps.Keys(modules))
+		pcbs, err := config.LoadPCBDefinitions(generator.PCBDefinitionsConfigDir)
+		if err != nil {
+			return errors.Join(errors.New("failed to load pcb definitions"), err)
+		}
+		options.PCBs = slices.Collect(maps.Keys(pcbs))
+		cfg, err := config.Load(generator.ConfigPath(configName))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "switch type names are not included: "+err.Error())
+		} else {
+			options.SwitchTypes = slices.Collect(maps.Keys(cfg.SwitchTypes))
+			options.RenderProfiles = slices.Collect(maps.Keys(cfg.Render.Profiles))
+		}
+		schema = config.Schema(options)
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode schema"), err)
+	}
+	data = append(data, '\n')
+	if schemaOutput == "" {
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	}
+	err = os.WriteFile(schemaOutput, data, 0o644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write schema"), err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+	"typemon/internal/preview"
+	"typemon/internal/watch"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultServePort = 8080
+
+var servePort int
+
+// Команда serve
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a live 3D preview of the model on localhost",
+	Long: "Serve a live 3D preview of the model on localhost. The model is regenerated and rendered on every change " +
+		"and the page reloads it. Without OpenSCAD the page shows the key layout.",
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().IntVar(&servePort, "port", defaultServePort, "Port of the preview server, it listens on 127.0.0.1 only")
+	serveCmd.Flags().StringVarP(&renderProfile, "profile", "p", "", "Render profile, overrides render.profile from the config")
+	serveCmd.Flags().BoolVar(&strictMode, "strict", false, "Fail on keycap collisions and parts cutting into keys instead of warning")
+	addRenderFlags(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watcher, err := watch.New(watch.DefaultDebounce)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	opts := renderOptions()
+	// the viewer loads STL only
+	opts.Format = "stl"
+	var renderer *generator.WatchRenderer
+	_, err = exec.LookPath(opts.OpenSCAD)
+	if err != nil {
+		fmt.Println("openscad binary not found: " + opts.OpenSCAD + ", the preview shows the key layout only")
+	} else {
+		renderer = generator.NewWatchRenderer(opts)
+		defer renderer.Stop()
+	}
+	server := preview.New(renderer != nil)
+	if renderer != nil {
+		renderer.Rendered = func(name string, output string) {
+			server.Update(func(state *preview.State) {
+				state.SetModel(name, strings.Fields(name)[0], output)
+			})
+		}
+		renderer.Finished = func(err error) {
+			server.Update(func(state *preview.State) {
+				state.Status = preview.StatusReady
+				if err != nil {
+					state.Errors = append(state.Errors, err.Error())
+				}
+			})
+		}
+	}
+
+	regenerate := func() {
+		if renderer != nil {
+			renderer.Stop()
+		}
+		server.Update(func(state *preview.State) {
+			state.Status = preview.StatusGenerating
+			state.Errors = nil
+		})
+		err := runPreviewGenerator(ctx, cmd.ErrOrStderr(), server, renderer)
+		if err != nil {
+			fmt.Println(err.Error())
+			server.Update(func(state *preview.State) {
+				// the page keeps showing the last good model under the errors
+				state.Status = preview.StatusReady
+				state.Errors = []string{err.Error()}
+			})
+		}
+		err = watcher.Set(generator.Dependencies(configName))
+		if err != nil {
+			fmt.Println("failed to update watched files: " + err.Error())
+		}
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe(ctx, servePort)
+	}()
+	regenerate()
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		err := watcher.Run(ctx, func(changed []string) {
+			for _, path := range changed {
+				fmt.Println("changed: " + path)
+			}
+			regenerate()
+		})
+		if err != nil {
+			fmt.Println("failed to watch: " + err.Error())
+		}
+	}()
+	err = <-served
+	stop()
+	// a regenerate in progress restarts the renderer, wait for it before the last stop
+	<-watched
+	if renderer != nil {
+		renderer.Stop()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("stopped serving")
+	return nil
+}
+
+// runPreviewGenerator generates the model, publishes the key layout of both
+// halves and starts rendering the model.
+func runPreviewGenerator(ctx context.Context, stderr io.Writer, server *preview.Server, renderer *generator.WatchRenderer) error {
+	generator, warnings, err := generator.New(configName, generatorOptions())
+	if err != nil {
+		return errors.Join(errors.New("failed to create generator"), err)
+	}
+	printWarnings(stderr, warnings)
+	problems, err := generator.Generate()
+	if err != nil {
+		return errors.Join(errors.New("failed to generate"), err)
+	}
+	printLayoutWarnings(stderr, problems)
+	fmt.Println("generated successfully")
+	var keys []preview.Key
+	for _, half := range config.Halves {
+		placed, err := generator.PlacedKeys(half)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, preview.NewKeys(half, placed)...)
+	}
+	server.Update(func(state *preview.State) {
+		state.Keys = keys
+		state.Status = preview.StatusReady
+		// set before the render starts, it may finish right away
+		if renderer != nil {
+			state.Status = preview.StatusRendering
+		}
+	})
+	if renderer == nil {
+		return nil
+	}
+	err = renderer.Start(ctx, generator)
+	if err != nil {
+		return errors.Join(errors.New("failed to start render"), err)
+	}
+	return nil
+}
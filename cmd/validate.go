@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+
+	"github.com/spf13/cobra"
+)
+
+// Команда validate
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate config and report every problem found",
+	RunE:  runValidate,
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	diagnostics, err := generator.Validate(configName)
+	if err != nil {
+		return errors.Join(errors.New("failed to validate config"), err)
+	}
+	for _, diagnostic := range diagnostics {
+		fmt.Println(diagnostic.String())
+	}
+	errorsCount := diagnostics.Count(config.SeverityError)
+	fmt.Printf("%d error(s), %d warning(s)\n", errorsCount, diagnostics.Count(config.SeverityWarning))
+	if errorsCount > 0 {
+		return errors.New("config is invalid")
+	}
+	return nil
+}
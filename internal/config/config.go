@@ -1,151 +1,398 @@
 package config
 
-import (
-	"errors"
-	"os"
-
-	"gopkg.in/yaml.v3"
-)
-
 // Config описывает корневую структуру конфигурации клавиатуры.
 type Config struct {
-	Units        Units                       `yaml:"units"`
-	Layout       Layout                      `yaml:"layout"`
-	Geometry     GeometryConfig              `yaml:"geometry"`
-	SwitchTypes  map[string]SwitchTypeConfig `yaml:"switch_types"`
-	Keywell      Keywell                     `yaml:"keywell"`
-	ThumbCluster ThumbCluster                `yaml:"thumb_cluster"`
-	Render       Render                      `yaml:"render"`
-	// Trackpoint    *Trackpoint                 `yaml:"trackpoint,omitempty"`
+	Units        Units                       `yaml:"units" desc:"Units of the config values"`
+	Layout       Layout                      `yaml:"layout" desc:"Keywell matrix size"`
+	Geometry     GeometryConfig              `yaml:"geometry" desc:"Case geometry parameters"`
+	SwitchTypes  map[string]SwitchTypeConfig `yaml:"switch_types" desc:"Switch types used by keys, keyed by name"`
+	Keywell      Keywell                     `yaml:"keywell" desc:"Main keywell surface"`
+	ThumbCluster ThumbCluster                `yaml:"thumb_cluster" desc:"Thumb cluster placement and keys"`
+	Bottom       Bottom                      `yaml:"bottom" desc:"Bottom plate closing the case"`
+	Controller   Controller                  `yaml:"controller" desc:"Controller board at a wall of the case"`
+	Trackpoint   Trackpoint                  `yaml:"trackpoint" desc:"Trackpoint between four keywell keys, set per half"`
+	Render       Render                      `yaml:"render" desc:"OpenSCAD render settings"`
 }
 
 type GeometryConfig struct {
-	PlaneThickness          float64 `yaml:"plane_thickness"`
-	SupportRadius           float64 `yaml:"support_radius"`
-	KeywellElevation        float64 `yaml:"keywell_elevation"`
-	WallBaseThickness       float64 `yaml:"wall_base_thickness"`
-	WallCenterOffsetPercent float64 `yaml:"wall_center_offset_percent"`
+	PlaneThickness          float64 `yaml:"plane_thickness" desc:"Thickness of the keywell plane" schema:"exclusiveMinimum=0"`
+	SupportRadius           float64 `yaml:"support_radius" desc:"Radius of the support shape, half of the smallest wall thickness" schema:"exclusiveMinimum=0"`
+	KeywellElevation        float64 `yaml:"keywell_elevation" desc:"Elevation of the lowest key above the base plane" schema:"minimum=0"`
+	WallBaseThickness       float64 `yaml:"wall_base_thickness" desc:"Wall thickness at the base plane" schema:"exclusiveMinimum=0"`
+	WallCenterOffsetPercent float64 `yaml:"wall_center_offset_percent" desc:"Fraction the wall base is pushed away from the outline center" schema:"minimum=0"`
+	KeyGap                  float64 `yaml:"key_gap" desc:"Gap between the supports of neighbouring keys, added to the key pitch" schema:"minimum=0"`
+	Tenting                 Tenting `yaml:"tenting" desc:"Rotation of the whole half around the Y axis, the walls follow it down to the desk"`
+}
+
+// KeyPitch возвращает расстояние между соседними клавишами по оси, на которой
+// самый большой колпачок имеет размер keycap.
+func (g GeometryConfig) KeyPitch(keycap float64) float64 {
+	return keycap + g.SupportRadius + g.KeyGap
+}
+
+const (
+	TentingPivotCenter = "center"
+	TentingPivotBack   = "back"
+	TentingPivotFront  = "front"
+)
+
+// Tenting наклоняет половину вокруг оси Y, нижняя пластина остаётся на столе.
+type Tenting struct {
+	Angle float64 `yaml:"angle" desc:"Tent angle around the Y axis, positive raises the back edge and negative the front edge" schema:"minimum=-60,maximum=60"`
+	Pivot string  `yaml:"pivot" desc:"Line the half is tented around: the keywell center, its back edge or its front edge" schema:"enum=center|back|front"`
 }
 
 type Units struct {
-	Length string `yaml:"length"`
-	Angle  string `yaml:"angle"`
+	Length string `yaml:"length" desc:"Length unit" schema:"enum=mm"`
+	Angle  string `yaml:"angle" desc:"Angle unit" schema:"enum=deg"`
 }
 
 type Layout struct {
-	Rows int `yaml:"rows"`
-	Cols int `yaml:"cols"`
+	Rows        int         `yaml:"rows" desc:"Number of keys in every column" schema:"minimum=2"`
+	Cols        int         `yaml:"cols" desc:"Number of keywell columns" schema:"minimum=5"`
+	ColumnRows  map[int]int `yaml:"column_rows,omitempty" desc:"Number of keys of individual columns keyed by column index, at most rows, keys are removed from the end of the column"`
+	MissingKeys []KeyIndex  `yaml:"missing_keys,omitempty" desc:"Keys removed from the matrix"`
+	ExtraKeys   []ExtraKey  `yaml:"extra_keys,omitempty" desc:"Keys outside the matrix attached to a neighbouring matrix key"`
+}
+
+// KeyIndex — позиция клавиши в матрице keywell.
+type KeyIndex struct {
+	Column int `yaml:"column" desc:"Column index of the key" schema:"minimum=0"`
+	Row    int `yaml:"row" desc:"Row index of the key" schema:"minimum=0"`
+}
+
+// ExtraKey — клавиша вне матрицы, например дополнительная клавиша мизинца на
+// home-ряду. Ставится на шаг матрицы от соседа и соединяется с ним.
+type ExtraKey struct {
+	Column     int      `yaml:"column" desc:"Column index of the neighbouring matrix key" schema:"minimum=0"`
+	Row        int      `yaml:"row" desc:"Row index of the neighbouring matrix key" schema:"minimum=0"`
+	Side       string   `yaml:"side" desc:"Side of the neighbour the key is attached to: inner and outer across the columns, back and front along the column" schema:"enum=inner|outer|back|front"`
+	Offset     Offset   `yaml:"offset" desc:"Position offset"`
+	Rotation   Rotation `yaml:"rotation" desc:"Rotation in degrees"`
+	SwitchType string   `yaml:"switch_type" desc:"Switch type of this key" schema:"ref=switch_type"`
+}
+
+// KeyRows возвращает число клавиш в колонке с учётом column_rows.
+func (l Layout) KeyRows(col int) int {
+	if rows, ok := l.ColumnRows[col]; ok {
+		return min(rows, l.Rows)
+	}
+	return l.Rows
+}
+
+// HasKey сообщает, есть ли в матрице клавиша в колонке col и ряду row.
+func (l Layout) HasKey(col int, row int) bool {
+	if col < 0 || col >= l.Cols || row < 0 || row >= l.KeyRows(col) {
+		return false
+	}
+	for _, missing := range l.MissingKeys {
+		if missing.Column == col && missing.Row == row {
+			return false
+		}
+	}
+	return true
+}
+
+// KeywellSwitchTypes возвращает типы свитчей клавиш, задающих шаг кейвелла:
+// каждой имеющейся клавиши матрицы и каждой дополнительной клавиши.
+func (c *Config) KeywellSwitchTypes() []string {
+	overrides := make(map[[2]int]string)
+	for _, modifier := range c.Keywell.Modifiers.Matrix {
+		if modifier.SwitchType != "" {
+			overrides[[2]int{modifier.Column, modifier.Row}] = modifier.SwitchType
+		}
+	}
+	var types []string
+	for col := range c.Layout.Cols {
+		for row := range c.Layout.Rows {
+			if !c.Layout.HasKey(col, row) {
+				continue
+			}
+			switchType, ok := overrides[[2]int{col, row}]
+			if !ok {
+				switchType = DefaultSwitchType
+			}
+			types = append(types, switchType)
+		}
+	}
+	for _, key := range c.Layout.ExtraKeys {
+		types = append(types, key.SwitchType)
+	}
+	return types
 }
 
 type SwitchTypeConfig struct {
-	Definition string                 `yaml:"definition"`
-	ExtraArgs  map[string]interface{} `yaml:"extra_args,omitempty"`
+	Definition string                 `yaml:"definition" desc:"Switch module definition from configs/switches" schema:"ref=switch_module"`
+	ExtraArgs  map[string]interface{} `yaml:"extra_args,omitempty" desc:"Overrides of the switch module extra arguments"`
+	KeycapSize *KeycapSize            `yaml:"keycap_size,omitempty" desc:"Keycap size of keys of this type, unset sizes fall back to the switch module min_keycap_size"`
+	PCB        string                 `yaml:"pcb,omitempty" desc:"PCB definition from configs/pcbs seated under every key of this type" schema:"ref=pcb"`
+}
+
+// ResolveKeycapSize возвращает размер колпачка типа свитча: заданные в
+// keycap_size значения, а остальные из min_keycap_size модуля.
+func (s SwitchTypeConfig) ResolveKeycapSize(module *SwitchModuleDefinition) KeycapSize {
+	size := module.MinKeycapSize
+	if s.KeycapSize == nil {
+		return size
+	}
+	if s.KeycapSize.Width != 0 {
+		size.Width = s.KeycapSize.Width
+	}
+	if s.KeycapSize.Height != 0 {
+		size.Height = s.KeycapSize.Height
+	}
+	if s.KeycapSize.Depth != 0 {
+		size.Depth = s.KeycapSize.Depth
+	}
+	return size
 }
 
 type Keywell struct {
-	TiltAngle              float64          `yaml:"tilt_angle"`
-	HorizontalRadius       float64          `yaml:"horizontal_radius"`
-	VerticalRadius         float64          `yaml:"vertical_radius"`
-	CenterOffset           Offset           `yaml:"center_offset"`
-	InnerLipSize           float64          `yaml:"inner_lip_size"`
-	OuterLipSize           float64          `yaml:"outer_lip_size"`
-	IndexFingerStartColumn int              `yaml:"index_finger_start_column"`
-	Modifiers              KeywellModifiers `yaml:"modifiers"`
+	TiltAngle              float64          `yaml:"tilt_angle" desc:"Tilt of the keywell around the X axis" schema:"minimum=-90,maximum=90"`
+	HorizontalRadius       float64          `yaml:"horizontal_radius" desc:"Keywell curvature radius across the columns" schema:"exclusiveMinimum=0"`
+	VerticalRadius         float64          `yaml:"vertical_radius" desc:"Keywell curvature radius along the columns" schema:"exclusiveMinimum=0"`
+	CenterOffset           Offset           `yaml:"center_offset" desc:"Offset of the curvature center from the matrix center"`
+	InnerLipSize           float64          `yaml:"inner_lip_size" desc:"Size of the lip along the inner column" schema:"minimum=0"`
+	OuterLipSize           float64          `yaml:"outer_lip_size" desc:"Size of the lip along the outer column" schema:"minimum=0"`
+	IndexFingerStartColumn int              `yaml:"index_finger_start_column" desc:"First column pressed by the index finger, columns before it are extra index columns" schema:"minimum=0"`
+	Curvature              Curvature        `yaml:"curvature" desc:"Shape of the keywell surface"`
+	Splay                  Splay            `yaml:"splay" desc:"Rotation of whole columns around the Z axis"`
+	Modifiers              KeywellModifiers `yaml:"modifiers" desc:"Per-finger, per-column, per-row and per-key adjustments, added together"`
+}
+
+const (
+	CurvatureSpherical   = "spherical"
+	CurvatureCylindrical = "cylindrical"
+	CurvatureParabolic   = "parabolic"
+	CurvatureElliptical  = "elliptical"
+	CurvatureExplicit    = "explicit"
+
+	CurvatureAxisVertical   = "vertical"
+	CurvatureAxisHorizontal = "horizontal"
+)
+
+// CurvatureProfiles lists the supported keywell curvature profiles.
+var CurvatureProfiles = []string{CurvatureSpherical, CurvatureCylindrical, CurvatureParabolic, CurvatureElliptical, CurvatureExplicit}
+
+// Curvature задаёт профиль поверхности keywell. Радиусы берутся из
+// horizontal_radius и vertical_radius.
+type Curvature struct {
+	Profile         string                  `yaml:"profile" desc:"spherical curves both axes by circles, cylindrical only one axis, parabolic and elliptical use those curves instead of circles, explicit takes heights and tilts from columns" schema:"enum=spherical|cylindrical|parabolic|elliptical|explicit"`
+	Axis            string                  `yaml:"axis" desc:"Curved axis of the cylindrical profile: vertical along the columns, horizontal across them" schema:"enum=vertical|horizontal"`
+	VerticalDepth   float64                 `yaml:"vertical_depth,omitempty" desc:"Elliptical profile semi-axis along Z for the vertical curve, defaults to vertical_radius" schema:"minimum=0"`
+	HorizontalDepth float64                 `yaml:"horizontal_depth,omitempty" desc:"Elliptical profile semi-axis along Z for the horizontal curve, defaults to horizontal_radius" schema:"minimum=0"`
+	Columns         map[int]CurvatureColumn `yaml:"columns,omitempty" desc:"Explicit profile heights and tilts keyed by column index"`
+}
+
+// CurvatureColumn — явные высоты и наклоны клавиш одной колонки.
+type CurvatureColumn struct {
+	Heights  []float64 `yaml:"heights" desc:"Z height of every key of the column, missing values are 0"`
+	Tilts    []float64 `yaml:"tilts" desc:"Tilt around the Y axis of every key of the column, missing values are 0"`
+	SideTilt float64   `yaml:"side_tilt" desc:"Tilt of the whole column around the X axis"`
+}
+
+const (
+	SplayPivotHomeRow = "home_row"
+	SplayPivotPoint   = "point"
+)
+
+// Splay разворачивает колонки веером вокруг оси Z.
+type Splay struct {
+	Pivot   string          `yaml:"pivot" desc:"home_row rotates every column around the front edge of its home row key, point rotates all columns around point" schema:"enum=home_row|point"`
+	HomeRow int             `yaml:"home_row" desc:"Row index of the home row, used with the home_row pivot" schema:"minimum=0"`
+	Point   Offset          `yaml:"point" desc:"Pivot point relative to the matrix center, used with the point pivot"`
+	Angles  map[int]float64 `yaml:"angles,omitempty" desc:"Splay angle keyed by column index, positive turns the column counterclockwise"`
 }
 
 type Offset struct {
-	X float64 `yaml:"x"`
-	Y float64 `yaml:"y"`
-	Z float64 `yaml:"z"`
+	X float64 `yaml:"x" desc:"Offset along the X axis"`
+	Y float64 `yaml:"y" desc:"Offset along the Y axis"`
+	Z float64 `yaml:"z" desc:"Offset along the Z axis"`
 }
 
 type Rotation struct {
-	X float64 `yaml:"x"`
-	Y float64 `yaml:"y"`
-	Z float64 `yaml:"z"`
+	X float64 `yaml:"x" desc:"Rotation around the X axis"`
+	Y float64 `yaml:"y" desc:"Rotation around the Y axis"`
+	Z float64 `yaml:"z" desc:"Rotation around the Z axis"`
 }
 
 type KeywellModifiers struct {
-	Finger  FingerModifiers           `yaml:"finger"`
-	Rows    map[int]RowColumnModifier `yaml:"rows"`
-	Columns map[int]RowColumnModifier `yaml:"columns"`
-	Matrix  []MatrixModifier          `yaml:"matrix"`
+	Finger  FingerModifiers           `yaml:"finger" desc:"Modifiers applied to every column of a finger"`
+	Rows    map[int]RowColumnModifier `yaml:"rows" desc:"Modifiers keyed by row index"`
+	Columns map[int]RowColumnModifier `yaml:"columns" desc:"Modifiers keyed by column index"`
+	Matrix  []MatrixModifier          `yaml:"matrix" desc:"Per-key modifiers, applied last"`
 }
 
 type FingerModifiers struct {
-	Index  FingerModifier `yaml:"index"`
-	Middle FingerModifier `yaml:"middle"`
-	Ring   FingerModifier `yaml:"ring"`
-	Pinky  FingerModifier `yaml:"pinky"`
+	Index  FingerModifier `yaml:"index" desc:"Index finger modifier"`
+	Middle FingerModifier `yaml:"middle" desc:"Middle finger modifier"`
+	Ring   FingerModifier `yaml:"ring" desc:"Ring finger modifier"`
+	Pinky  FingerModifier `yaml:"pinky" desc:"Pinky finger modifier"`
 }
 
 type FingerModifier struct {
-	Offset Offset  `yaml:"offset"`
-	Tilt   float64 `yaml:"tilt,omitempty"`
+	Offset Offset  `yaml:"offset" desc:"Position offset"`
+	Tilt   float64 `yaml:"tilt,omitempty" desc:"Tilt around the X axis"`
 }
 
 type RowColumnModifier struct {
-	Offset Offset  `yaml:"offset"`
-	Tilt   float64 `yaml:"tilt"`
+	Offset Offset  `yaml:"offset" desc:"Position offset"`
+	Tilt   float64 `yaml:"tilt" desc:"Tilt, around the Y axis for rows and around the X axis for columns, see keywell.splay for rotation around the Z axis"`
 }
 
 type MatrixModifier struct {
-	Column                int      `yaml:"column"`
-	Row                   int      `yaml:"row"`
-	Offset                Offset   `yaml:"offset"`
-	Rotation              Rotation `yaml:"rotation"`
-	IgnoreFingerModifiers bool     `yaml:"ignore_finger_modifiers"`
-	IgnoreColumnModifiers bool     `yaml:"ignore_column_modifiers"`
-	IgnoreRowModifiers    bool     `yaml:"ignore_row_modifiers"`
-	SwitchType            string   `yaml:"switch_type"`
+	Column                int      `yaml:"column" desc:"Column index of the key" schema:"minimum=0"`
+	Row                   int      `yaml:"row" desc:"Row index of the key" schema:"minimum=0"`
+	Offset                Offset   `yaml:"offset" desc:"Position offset"`
+	Rotation              Rotation `yaml:"rotation" desc:"Rotation in degrees"`
+	IgnoreFingerModifiers bool     `yaml:"ignore_finger_modifiers" desc:"Do not apply the finger modifier to this key"`
+	IgnoreColumnModifiers bool     `yaml:"ignore_column_modifiers" desc:"Do not apply the column modifier to this key"`
+	IgnoreRowModifiers    bool     `yaml:"ignore_row_modifiers" desc:"Do not apply the row modifier to this key"`
+	SwitchType            string   `yaml:"switch_type" desc:"Switch type of this key" schema:"ref=switch_type"`
 }
 
 type ThumbCluster struct {
-	OriginColumnIndex int              `yaml:"origin_column_index"`
-	Offset            Offset           `yaml:"offset"`
-	Rotation          Rotation         `yaml:"rotation"`
-	Keys              map[int]ThumbKey `yaml:"keys"`
+	OriginColumnIndex int              `yaml:"origin_column_index" desc:"Keywell column the cluster is attached to" schema:"minimum=0"`
+	Offset            Offset           `yaml:"offset" desc:"Position offset"`
+	Rotation          Rotation         `yaml:"rotation" desc:"Rotation in degrees"`
+	Keys              map[int]ThumbKey `yaml:"keys" desc:"Thumb keys keyed by index, contiguous from 0 up to 5"`
 }
 
 type ThumbKey struct {
-	Offset   Offset   `yaml:"offset"`
-	Rotation Rotation `yaml:"rotation"`
-	Type     string   `yaml:"type"`
-}
-
-// type Trackpoint struct {
-// 	LeftSide  TrackpointSide `yaml:"left_side"`
-// 	RightSide TrackpointSide `yaml:"right_side"`
-// }
-
-// type TrackpointSide struct {
-// 	ColumnIndex     int     `yaml:"column_index"`
-// 	RowIndex        int     `yaml:"row_index"`
-// 	Enabled         bool    `yaml:"enabled"`
-// 	OffsetX         float64 `yaml:"offset_x"`
-// 	OffsetY         float64 `yaml:"offset_y"`
-// 	OffsetZ         float64 `yaml:"offset_z"`
-// 	HoleDiameterMM  float64 `yaml:"hole_diameter_mm"`
-// 	MountDiameterMM float64 `yaml:"mount_diameter_mm"`
-// 	MountDepthMM    float64 `yaml:"mount_depth_mm"`
-// }
+	Offset   Offset   `yaml:"offset" desc:"Position offset"`
+	Rotation Rotation `yaml:"rotation" desc:"Rotation in degrees"`
+	Type     string   `yaml:"type" desc:"Switch type of this key" schema:"ref=switch_type"`
+}
+
+// Trackpoint задаёт трекпойнт каждой половины отдельно.
+type Trackpoint struct {
+	LeftSide  TrackpointSide `yaml:"left_side" desc:"Trackpoint of the left half"`
+	RightSide TrackpointSide `yaml:"right_side" desc:"Trackpoint of the right half"`
+}
+
+// TrackpointSide — трекпойнт одной половины. Ставится между клавишами
+// column_index и column_index+1, row_index и row_index+1.
+type TrackpointSide struct {
+	Enabled       bool    `yaml:"enabled" desc:"Add a trackpoint to this half"`
+	ColumnIndex   int     `yaml:"column_index" desc:"The trackpoint is placed between this column and the next one" schema:"minimum=0"`
+	RowIndex      int     `yaml:"row_index" desc:"The trackpoint is placed between this row and the next one" schema:"minimum=0"`
+	Offset        Offset  `yaml:"offset" desc:"Position offset from the point between the four keys, in key coordinates mirrored with the half"`
+	HoleDiameter  float64 `yaml:"hole_diameter" desc:"Diameter of the stem hole through the keywell plane" schema:"exclusiveMinimum=0"`
+	MountDiameter float64 `yaml:"mount_diameter" desc:"Diameter of the mount boss under the keywell plane" schema:"exclusiveMinimum=0"`
+	MountDepth    float64 `yaml:"mount_depth" desc:"Depth of the mount boss below the keywell plane" schema:"minimum=0"`
+	Clearance     float64 `yaml:"clearance" desc:"Gap between the stem hole and the keycap cutouts, colliding cutouts are shrunk" schema:"minimum=0"`
+}
+
+type Bottom struct {
+	Thickness float64      `yaml:"thickness" desc:"Bottom plate thickness" schema:"exclusiveMinimum=0"`
+	Clearance float64      `yaml:"clearance" desc:"Gap between the plate edge and the wall feet" schema:"minimum=0"`
+	Screws    BottomScrews `yaml:"screws" desc:"Screws holding the plate to bosses in the walls"`
+}
+
+type BottomScrews struct {
+	Count             int     `yaml:"count" desc:"Number of screws spread along the outline, 0 disables them" schema:"minimum=0"`
+	HoleDiameter      float64 `yaml:"hole_diameter" desc:"Pilot hole diameter in the bosses" schema:"exclusiveMinimum=0"`
+	ClearanceDiameter float64 `yaml:"clearance_diameter" desc:"Screw hole diameter in the plate" schema:"exclusiveMinimum=0"`
+	HeadDiameter      float64 `yaml:"head_diameter" desc:"Screw head recess diameter in the plate" schema:"exclusiveMinimum=0"`
+	HeadDepth         float64 `yaml:"head_depth" desc:"Screw head recess depth in the plate" schema:"minimum=0"`
+	BossDiameter      float64 `yaml:"boss_diameter" desc:"Outer diameter of the screw bosses" schema:"exclusiveMinimum=0"`
+	BossHeight        float64 `yaml:"boss_height" desc:"Height of the screw bosses above the plate" schema:"exclusiveMinimum=0"`
+}
+
+const (
+	ControllerNiceNano   = "nice_nano"
+	ControllerProMicro   = "pro_micro"
+	ControllerRP2040Zero = "rp2040_zero"
+	ControllerCustom     = "custom"
+
+	ControllerHolderCradle = "cradle"
+	ControllerHolderPocket = "pocket"
+)
+
+// ControllerBoards holds the nominal sizes of the board presets, all of them
+// with the USB receptacle on top at the short edge.
+var ControllerBoards = map[string]ControllerSize{
+	ControllerNiceNano:   {Width: 18, Length: 33.5, Thickness: 1.6, ComponentHeight: 3.3},
+	ControllerProMicro:   {Width: 18, Length: 33, Thickness: 1.6, ComponentHeight: 3.5},
+	ControllerRP2040Zero: {Width: 18, Length: 23.5, Thickness: 1.0, ComponentHeight: 3.3},
+}
+
+// Controller описывает плату контроллера и её место у стенки корпуса.
+type Controller struct {
+	Enabled   bool            `yaml:"enabled" desc:"Add a controller cavity, holder and USB cutout to the case"`
+	Board     string          `yaml:"board" desc:"Board preset, custom takes the dimensions from size" schema:"enum=nice_nano|pro_micro|rp2040_zero|custom"`
+	Size      *ControllerSize `yaml:"size,omitempty" desc:"Board dimensions, required for custom boards, set values override the preset"`
+	Wall      string          `yaml:"wall" desc:"Wall segment of the case outline the USB port goes through" schema:"enum=inner|back|outer"`
+	Position  float64         `yaml:"position" desc:"Place along the wall segment, 0 at its start and 1 at its end in outline order: inner lip front to back, back wall inner to outer, outer lip back to front" schema:"minimum=0,maximum=1"`
+	Elevation float64         `yaml:"elevation" desc:"Gap between the bottom plate and the board" schema:"minimum=0"`
+	Clearance float64         `yaml:"clearance" desc:"Gap around the board in its cavity" schema:"minimum=0"`
+	USB       ControllerUSB   `yaml:"usb" desc:"USB-C cutout through the wall"`
+	Holder    Holder          `yaml:"holder" desc:"Holder keeping the board in place"`
+}
+
+// ControllerSize — габариты платы контроллера.
+type ControllerSize struct {
+	Width           float64 `yaml:"width,omitempty" desc:"Board width along the wall" schema:"exclusiveMinimum=0"`
+	Length          float64 `yaml:"length,omitempty" desc:"Board length from the wall inwards" schema:"exclusiveMinimum=0"`
+	Thickness       float64 `yaml:"thickness,omitempty" desc:"PCB thickness" schema:"exclusiveMinimum=0"`
+	ComponentHeight float64 `yaml:"component_height,omitempty" desc:"Height of the components on top of the board, the USB receptacle included" schema:"minimum=0"`
+}
+
+// ControllerUSB — вырез под разъём USB-C в стенке.
+type ControllerUSB struct {
+	Width     float64 `yaml:"width" desc:"Cutout width, the receptacle is about 9mm wide, plugs with thick overmolds need more" schema:"exclusiveMinimum=0"`
+	Height    float64 `yaml:"height" desc:"Cutout height" schema:"exclusiveMinimum=0"`
+	Elevation float64 `yaml:"elevation" desc:"Height of the cutout center above the top of the board" schema:"minimum=0"`
+	Chamfer   float64 `yaml:"chamfer" desc:"Chamfer of the cutout on the outer side of the wall" schema:"minimum=0"`
+}
+
+// Holder — держатель платы: рамка, добавленная к корпусу, или только карман.
+type Holder struct {
+	Type          string  `yaml:"type" desc:"cradle adds a frame around the board to the case, pocket only cuts the cavity out of it" schema:"enum=cradle|pocket"`
+	WallThickness float64 `yaml:"wall_thickness" desc:"Cradle wall thickness" schema:"exclusiveMinimum=0"`
+	Ledge         float64 `yaml:"ledge" desc:"Width of the cradle ledge the board rests on when it is elevated" schema:"minimum=0"`
+}
+
+// ResolveSize возвращает габариты платы: пресет с заданными в size
+// значениями поверх него.
+func (c Controller) ResolveSize() ControllerSize {
+	size := ControllerBoards[c.Board]
+	if c.Size == nil {
+		return size
+	}
+	if c.Size.Width != 0 {
+		size.Width = c.Size.Width
+	}
+	if c.Size.Length != 0 {
+		size.Length = c.Size.Length
+	}
+	if c.Size.Thickness != 0 {
+		size.Thickness = c.Size.Thickness
+	}
+	if c.Size.ComponentHeight != 0 {
+		size.ComponentHeight = c.Size.ComponentHeight
+	}
+	return size
+}
 
 type Render struct {
-	Fn    int  `yaml:"$fn"`
-	Debug bool `yaml:"debug,omitempty"`
+	Profile  string                   `yaml:"profile" desc:"Render profile used when --profile is not given" schema:"ref=render_profile"`
+	Profiles map[string]RenderProfile `yaml:"profiles,omitempty" desc:"Render profiles keyed by name, built-in draft, preview and final can be overridden"`
+}
+
+// RenderProfile задаёт качество рендера OpenSCAD.
+type RenderProfile struct {
+	Fn    int     `yaml:"fn" desc:"OpenSCAD $fn, fixed number of fragments, 0 uses $fa and $fs" schema:"minimum=0"`
+	Fa    float64 `yaml:"fa" desc:"OpenSCAD $fa, minimum fragment angle" schema:"exclusiveMinimum=0"`
+	Fs    float64 `yaml:"fs" desc:"OpenSCAD $fs, minimum fragment size" schema:"exclusiveMinimum=0"`
+	Debug bool    `yaml:"debug" desc:"Show switch cutouts in the model, only for previews"`
 }
 
 // Load загружает YAML-конфиг из файла по указанному пути.
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	doc, err := LoadDocument(path)
 	if err != nil {
-		return nil, errors.Join(errors.New("failed to read config file"), err)
-	}
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, errors.Join(errors.New("failed to unmarshal yaml"), err)
+		return nil, err
 	}
-
-	return &cfg, nil
+	return doc.Config, nil
 }
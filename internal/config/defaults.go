@@ -0,0 +1,154 @@
+package config
+
+const (
+	DefaultSwitchType    = "regular"
+	DefaultRenderProfile = "preview"
+	DefaultThumbKeys     = 3
+	MaxThumbKeys         = 6
+)
+
+// BuiltinRenderProfiles are always available and may be partially
+// overridden in render.profiles.
+var BuiltinRenderProfiles = map[string]RenderProfile{
+	"draft":   {Fn: 12, Fa: 12, Fs: 2},
+	"preview": {Fn: 0, Fa: 6, Fs: 0.5, Debug: true},
+	"final":   {Fn: 96, Fa: 1, Fs: 0.2},
+}
+
+var defaultTrackpointSide = TrackpointSide{
+	HoleDiameter:  5,
+	MountDiameter: 8,
+	MountDepth:    4,
+	Clearance:     0.5,
+}
+
+// Default возвращает конфиг с документированными значениями по умолчанию.
+// Значения из YAML накладываются поверх него при загрузке.
+func Default() Config {
+	return Config{
+		Units: Units{
+			Length: "mm",
+			Angle:  "deg",
+		},
+		Layout: Layout{
+			Rows: 4,
+			Cols: 5,
+		},
+		Geometry: GeometryConfig{
+			PlaneThickness:          2.2,
+			SupportRadius:           1,
+			KeywellElevation:        5,
+			WallBaseThickness:       4,
+			WallCenterOffsetPercent: 0.05,
+			KeyGap:                  0.5,
+			Tenting: Tenting{
+				Pivot: TentingPivotCenter,
+			},
+		},
+		Keywell: Keywell{
+			HorizontalRadius:       120,
+			VerticalRadius:         100,
+			InnerLipSize:           10,
+			OuterLipSize:           10,
+			IndexFingerStartColumn: 1,
+			Curvature: Curvature{
+				Profile: CurvatureSpherical,
+				Axis:    CurvatureAxisVertical,
+			},
+			Splay: Splay{
+				Pivot:   SplayPivotHomeRow,
+				HomeRow: 1,
+			},
+		},
+		Bottom: Bottom{
+			Thickness: 2,
+			Clearance: 0.2,
+			Screws: BottomScrews{
+				Count:             4,
+				HoleDiameter:      1.6,
+				ClearanceDiameter: 2.4,
+				HeadDiameter:      4.2,
+				HeadDepth:         1.2,
+				BossDiameter:      5,
+				BossHeight:        4,
+			},
+		},
+		Controller: Controller{
+			Board:     ControllerNiceNano,
+			Wall:      "back",
+			Position:  0.5,
+			Clearance: 0.3,
+			USB: ControllerUSB{
+				Width:     9.5,
+				Height:    3.6,
+				Elevation: 1.6,
+				Chamfer:   1,
+			},
+			Holder: Holder{
+				Type:          ControllerHolderCradle,
+				WallThickness: 1.5,
+				Ledge:         1,
+			},
+		},
+		Trackpoint: Trackpoint{
+			LeftSide:  defaultTrackpointSide,
+			RightSide: defaultTrackpointSide,
+		},
+		Render: Render{
+			Profile: DefaultRenderProfile,
+		},
+	}
+}
+
+// applyDerivedDefaults fills the defaults that depend on other values or on
+// whether a field is present in the document at all.
+func (d *Document) applyDerivedDefaults() {
+	cfg := d.Config
+	if !d.Has("thumb_cluster", "origin_column_index") {
+		cfg.ThumbCluster.OriginColumnIndex = cfg.Keywell.IndexFingerStartColumn
+	}
+	if !d.Has("thumb_cluster", "keys") {
+		cfg.ThumbCluster.Keys = make(map[int]ThumbKey, DefaultThumbKeys)
+		for idx := range DefaultThumbKeys {
+			cfg.ThumbCluster.Keys[idx] = ThumbKey{}
+		}
+	}
+	for idx, key := range cfg.ThumbCluster.Keys {
+		if key.Type == "" {
+			key.Type = DefaultSwitchType
+			cfg.ThumbCluster.Keys[idx] = key
+		}
+	}
+
+	for i, key := range cfg.Layout.ExtraKeys {
+		if key.SwitchType == "" {
+			cfg.Layout.ExtraKeys[i].SwitchType = DefaultSwitchType
+		}
+	}
+
+	if cfg.Render.Profiles == nil {
+		cfg.Render.Profiles = make(map[string]RenderProfile)
+	}
+	for name, builtin := range BuiltinRenderProfiles {
+		profile, ok := cfg.Render.Profiles[name]
+		if !ok {
+			cfg.Render.Profiles[name] = builtin
+			continue
+		}
+		// fields left out of an overridden built-in profile keep its values
+		path := []string{"render", "profiles", name}
+		if !d.Has(append(path, "fn")...) {
+			profile.Fn = builtin.Fn
+		}
+		if !d.Has(append(path, "fa")...) {
+			profile.Fa = builtin.Fa
+		}
+		if !d.Has(append(path, "fs")...) {
+			profile.Fs = builtin.Fs
+		}
+		if !d.Has(append(path, "debug")...) {
+			profile.Debug = builtin.Debug
+		}
+		cfg.Render.Profiles[name] = profile
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document хранит загруженный конфиг вместе с исходным YAML-деревом,
+// чтобы диагностики могли ссылаться на строки и колонки файла.
+type Document struct {
+	Path   string
+	Config *Config
+	// Files lists the config file and every config it extends.
+	Files   []string
+	root    *yaml.Node
+	sources map[*yaml.Node]string
+	// halves holds the left and right override blocks taken out of root.
+	halves map[string]*yaml.Node
+}
+
+// LoadDocument загружает YAML-конфиг вместе с конфигами из extends,
+// заполняет пропущенные поля значениями по умолчанию и сохраняет
+// объединённое YAML-дерево.
+func LoadDocument(path string) (*Document, error) {
+	l := newLoader()
+	root, err := l.load(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	halves, err := takeHalves(root)
+	if err != nil {
+		return nil, errors.Join(errors.New("invalid half overrides"), err)
+	}
+
+	cfg := Default()
+	if err := root.Decode(&cfg); err != nil {
+		return nil, errors.Join(errors.New("failed to unmarshal yaml"), err)
+	}
+
+	doc := &Document{Path: path, Config: &cfg, Files: l.files, root: root, sources: l.sources, halves: halves}
+	doc.applyDerivedDefaults()
+	return doc, nil
+}
+
+// Half возвращает конфиг одной половины: блок left или right, глубоко
+// слитый с общими настройками. Без блока половина совпадает с общим
+// конфигом.
+func (d *Document) Half(half string) (*Document, error) {
+	if !slices.Contains(Halves, half) {
+		return nil, errors.New("unknown half: " + half)
+	}
+	root := d.clone(d.root)
+	if overlay, ok := d.halves[half]; ok {
+		root = mergeNodes(root, d.clone(overlay), nil)
+	}
+
+	cfg := Default()
+	if err := root.Decode(&cfg); err != nil {
+		return nil, errors.Join(errors.New("failed to unmarshal "+half+" half"), err)
+	}
+
+	doc := &Document{Path: d.Path, Config: &cfg, Files: d.Files, root: root, sources: d.sources}
+	doc.applyDerivedDefaults()
+	return doc, nil
+}
+
+// clone deep-copies a YAML tree, the copies keep the source file of the
+// original nodes for diagnostics.
+func (d *Document) clone(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = d.clone(child)
+	}
+	if file, ok := d.sources[node]; ok {
+		d.sources[&copied] = file
+	}
+	return &copied
+}
+
+// Node returns the YAML node at the given path and whether the whole path
+// exists. When a part of the path is missing, the deepest existing node is
+// returned instead. Sequence elements are addressed as "[i]".
+func (d *Document) Node(path ...string) (*yaml.Node, bool) {
+	node := d.root
+	if node == nil {
+		return nil, false
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, segment := range path {
+		next := childNode(node, segment)
+		if next == nil {
+			return node, false
+		}
+		node = next
+	}
+	return node, true
+}
+
+// Position returns the file, line and column of the node at path, falling
+// back to the closest existing parent.
+func (d *Document) Position(path ...string) (string, int, int) {
+	node, _ := d.Node(path...)
+	if node == nil {
+		return d.Path, 0, 0
+	}
+	file, ok := d.sources[node]
+	if !ok {
+		file = d.Path
+	}
+	return file, node.Line, node.Column
+}
+
+// Has reports whether the value at path is explicitly set in the document.
+func (d *Document) Has(path ...string) bool {
+	_, ok := d.Node(path...)
+	return ok
+}
+
+func childNode(node *yaml.Node, segment string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if !strings.HasPrefix(segment, "[") || !strings.HasSuffix(segment, "]") {
+			return nil
+		}
+		idx, err := strconv.Atoi(segment[1 : len(segment)-1])
+		if err != nil || idx < 0 || idx >= len(node.Content) {
+			return nil
+		}
+		return node.Content[idx]
+	case yaml.AliasNode:
+		return childNode(node.Alias, segment)
+	}
+	return nil
+}
+
+func formatPath(path []string) string {
+	var b strings.Builder
+	for i, segment := range path {
+		if i > 0 && !strings.HasPrefix(segment, "[") {
+			b.WriteString(".")
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
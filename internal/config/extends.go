@@ -0,0 +1,222 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configExtension = ".yml"
+	extendsKey      = "extends"
+)
+
+// ResolvePath возвращает путь к конфигу по его имени: имя без расширения
+// дополняется ".yml", относительные пути считаются от dir.
+func ResolvePath(dir string, name string) string {
+	if ext := filepath.Ext(name); ext != ".yml" && ext != ".yaml" {
+		name += configExtension
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+type loader struct {
+	sources map[*yaml.Node]string
+	files   []string
+}
+
+func newLoader() *loader {
+	return &loader{sources: make(map[*yaml.Node]string)}
+}
+
+func formatChain(chain []string) string {
+	return strings.Join(chain, " -> ")
+}
+
+// load reads the config at path and merges it over every config it extends.
+// chain holds the files that led to this one and is used for cycle detection.
+func (l *loader) load(path string, chain []string) (*yaml.Node, error) {
+	path = filepath.Clean(path)
+	chain = append(slices.Clone(chain), path)
+	if slices.Contains(chain[:len(chain)-1], path) {
+		return nil, fmt.Errorf("config extends cycle: %s", formatChain(chain))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to read config file: %s", formatChain(chain)), err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to parse yaml: %s", formatChain(chain)), err)
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(root.Content) > 0 {
+		node = root.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config root must be a mapping: %s", formatChain(chain))
+	}
+	l.record(node, path)
+	if !slices.Contains(l.files, path) {
+		l.files = append(l.files, path)
+	}
+
+	parents, err := takeExtends(node)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid extends: %s", formatChain(chain)), err)
+	}
+	var base *yaml.Node
+	for _, parent := range parents {
+		parentNode, err := l.load(ResolvePath(filepath.Dir(path), parent), chain)
+		if err != nil {
+			return nil, err
+		}
+		if base == nil {
+			base = parentNode
+		} else {
+			base = mergeNodes(base, parentNode, nil)
+		}
+	}
+	if base == nil {
+		return node, nil
+	}
+	return mergeNodes(base, node, nil), nil
+}
+
+func (l *loader) record(node *yaml.Node, path string) {
+	l.sources[node] = path
+	for _, child := range node.Content {
+		l.record(child, path)
+	}
+}
+
+// takeExtends removes the extends key from the mapping and returns the
+// parent config names it lists.
+func takeExtends(node *yaml.Node) ([]string, error) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != extendsKey {
+			continue
+		}
+		value := node.Content[i+1]
+		node.Content = slices.Delete(node.Content, i, i+2)
+		switch value.Kind {
+		case yaml.ScalarNode:
+			return []string{value.Value}, nil
+		case yaml.SequenceNode:
+			parents := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("line %d: extends entries must be config names", item.Line)
+				}
+				parents = append(parents, item.Value)
+			}
+			return parents, nil
+		default:
+			return nil, fmt.Errorf("line %d: extends must be a config name or a list of names", value.Line)
+		}
+	}
+	return nil, nil
+}
+
+const (
+	HalfLeft  = "left"
+	HalfRight = "right"
+)
+
+// Halves lists the keyboard halves that can override the shared settings.
+var Halves = []string{HalfLeft, HalfRight}
+
+// halfSharedKeys can not be overridden by a half: the halves are rendered
+// with the same settings and extends is resolved before the halves are split.
+var halfSharedKeys = []string{extendsKey, HalfLeft, HalfRight, "render"}
+
+// takeHalves removes the left and right override blocks from the merged
+// config root and returns them by half.
+func takeHalves(root *yaml.Node) (map[string]*yaml.Node, error) {
+	halves := make(map[string]*yaml.Node)
+	for _, half := range Halves {
+		idx := mappingIndex(root, half)
+		if idx < 0 {
+			continue
+		}
+		value := root.Content[idx+1]
+		root.Content = slices.Delete(root.Content, idx, idx+2)
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: %s must be a mapping of overridden settings", value.Line, half)
+		}
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if key := value.Content[i]; slices.Contains(halfSharedKeys, key.Value) {
+				return nil, fmt.Errorf("line %d: %s can not be overridden in %s, it is shared by both halves", key.Line, key.Value, half)
+			}
+		}
+		halves[half] = value
+	}
+	return halves, nil
+}
+
+// mergeNodes deep-merges overlay into base. Mappings are merged key by key,
+// keywell.modifiers.matrix entries are matched on (column, row), anything
+// else in overlay replaces the base value.
+func mergeNodes(base *yaml.Node, overlay *yaml.Node, path []string) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			if idx := mappingIndex(base, key.Value); idx >= 0 {
+				base.Content[idx+1] = mergeNodes(base.Content[idx+1], value, append(slices.Clone(path), key.Value))
+			} else {
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && formatPath(path) == "keywell.modifiers.matrix":
+		for _, item := range overlay.Content {
+			matched := false
+			for i, baseItem := range base.Content {
+				if matrixKey(baseItem) == matrixKey(item) {
+					base.Content[i] = mergeNodes(baseItem, item, nil)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	default:
+		return overlay
+	}
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func matrixKey(node *yaml.Node) [2]string {
+	key := [2]string{"0", "0"}
+	if idx := mappingIndex(node, "column"); idx >= 0 {
+		key[0] = node.Content[idx+1].Value
+	}
+	if idx := mappingIndex(node, "row"); idx >= 0 {
+		key[1] = node.Content[idx+1].Value
+	}
+	return key
+}
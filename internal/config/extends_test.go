@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigs writes the configs keyed by file name into a temporary
+// directory and returns it.
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExtendsMergesMatrixEntries(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"base.yml": `keywell:
+  modifiers:
+    matrix:
+      - column: 1
+        row: 2
+        offset:
+          z: 1
+        switch_type: regular
+      - column: 0
+        row: 0
+        offset:
+          x: 2
+`,
+		"child.yml": `extends: base
+keywell:
+  modifiers:
+    matrix:
+      - column: 1
+        row: 2
+        offset:
+          y: 3
+      - column: 3
+        row: 1
+        offset:
+          z: 4
+`,
+	})
+	doc, err := LoadDocument(filepath.Join(dir, "child.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MatrixModifier{
+		// merged key by key, the base switch type stays
+		{Column: 1, Row: 2, Offset: Offset{Y: 3, Z: 1}, SwitchType: "regular"},
+		{Column: 0, Row: 0, Offset: Offset{X: 2}},
+		{Column: 3, Row: 1, Offset: Offset{Z: 4}},
+	}
+	got := doc.Config.Keywell.Modifiers.Matrix
+	if len(got) != len(want) {
+		t.Fatalf("matrix = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("matrix[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtendsList(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"a.yml": "geometry:\n  key_gap: 1\n  plane_thickness: 3\n",
+		"b.yml": "geometry:\n  key_gap: 2\n",
+		"child.yml": "extends:\n  - a\n  - b\n" +
+			"geometry:\n  support_radius: 4\n",
+	})
+	doc, err := LoadDocument(filepath.Join(dir, "child.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	geometry := doc.Config.Geometry
+	// later parents override earlier ones, the config itself overrides both
+	if geometry.KeyGap != 2 || geometry.PlaneThickness != 3 || geometry.SupportRadius != 4 {
+		t.Errorf("key_gap %v, plane_thickness %v, support_radius %v, want 2, 3 and 4",
+			geometry.KeyGap, geometry.PlaneThickness, geometry.SupportRadius)
+	}
+	// untouched settings keep their defaults
+	if geometry.WallBaseThickness != Default().Geometry.WallBaseThickness {
+		t.Errorf("wall_base_thickness = %v, want the default", geometry.WallBaseThickness)
+	}
+	if len(doc.Files) != 3 {
+		t.Errorf("files = %v, want the config and both parents", doc.Files)
+	}
+}
+
+func TestExtendsCycle(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"a.yml": "extends: b\n",
+		"b.yml": "extends: a\n",
+	})
+	_, err := LoadDocument(filepath.Join(dir, "a.yml"))
+	if err == nil {
+		t.Fatal("want a cycle error")
+	}
+	a, b := filepath.Join(dir, "a.yml"), filepath.Join(dir, "b.yml")
+	if want := "config extends cycle: " + a + " -> " + b + " -> " + a; !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
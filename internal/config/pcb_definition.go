@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PCBMountScrew = "screw"
+	PCBMountClip  = "clip"
+	PCBMountNone  = "none"
+)
+
+// PCBDefinition описывает плату под одной клавишей. Координаты задаются в
+// системе клавиши: центр свитча, X вдоль колонки, Y поперёк колонок.
+type PCBDefinition struct {
+	Outline   PCBOutline `yaml:"outline" desc:"Rounded rectangle outline of the PCB"`
+	Thickness float64    `yaml:"thickness" desc:"PCB thickness" schema:"exclusiveMinimum=0"`
+	Standoff  float64    `yaml:"standoff,omitempty" desc:"Gap between the bottom of the keywell plane and the top of the PCB" schema:"minimum=0"`
+	Socket    PCBSocket  `yaml:"socket" desc:"Hotswap socket on the bottom of the PCB"`
+	Mount     PCBMount   `yaml:"mount" desc:"How the PCB is held in its seat"`
+}
+
+type PCBOutline struct {
+	Width        float64  `yaml:"width" desc:"Outline size along the X axis" schema:"exclusiveMinimum=0"`
+	Height       float64  `yaml:"height" desc:"Outline size along the Y axis" schema:"exclusiveMinimum=0"`
+	CornerRadius float64  `yaml:"corner_radius,omitempty" desc:"Radius of the outline corners" schema:"minimum=0"`
+	Offset       PCBPoint `yaml:"offset,omitempty" desc:"Outline center relative to the switch center"`
+}
+
+// PCBPoint — точка на плате относительно центра свитча.
+type PCBPoint struct {
+	X float64 `yaml:"x" desc:"Position along the X axis"`
+	Y float64 `yaml:"y" desc:"Position along the Y axis"`
+}
+
+type PCBSocket struct {
+	Position PCBPoint `yaml:"position" desc:"Socket center relative to the switch center"`
+	Width    float64  `yaml:"width" desc:"Socket size along the X axis" schema:"exclusiveMinimum=0"`
+	Height   float64  `yaml:"height" desc:"Socket size along the Y axis" schema:"exclusiveMinimum=0"`
+	Depth    float64  `yaml:"depth" desc:"Socket height below the PCB" schema:"exclusiveMinimum=0"`
+}
+
+type PCBMount struct {
+	Type  string    `yaml:"type" desc:"screw fastens the PCB to bosses under the keywell plane, clip holds it with snap arms, none only aligns it with a rim" schema:"enum=screw|clip|none"`
+	Screw PCBScrews `yaml:"screw,omitempty" desc:"Screw mount, used with type screw"`
+	Clip  PCBClips  `yaml:"clip,omitempty" desc:"Clip mount, used with type clip"`
+	Rim   PCBRim    `yaml:"rim,omitempty" desc:"Rim around the PCB outline aligning it in the seat"`
+}
+
+type PCBScrews struct {
+	HoleDiameter float64    `yaml:"hole_diameter" desc:"Pilot hole diameter in the bosses" schema:"exclusiveMinimum=0"`
+	BossDiameter float64    `yaml:"boss_diameter" desc:"Boss diameter, the bosses fill the standoff" schema:"exclusiveMinimum=0"`
+	Depth        float64    `yaml:"depth" desc:"Pilot hole depth above the PCB" schema:"exclusiveMinimum=0"`
+	Positions    []PCBPoint `yaml:"positions" desc:"Screw positions relative to the switch center"`
+}
+
+type PCBClips struct {
+	Width     float64  `yaml:"width" desc:"Clip arm width along the PCB edge" schema:"exclusiveMinimum=0"`
+	Thickness float64  `yaml:"thickness" desc:"Clip arm thickness" schema:"exclusiveMinimum=0"`
+	Hook      float64  `yaml:"hook" desc:"How far the hook reaches under the PCB edge" schema:"exclusiveMinimum=0"`
+	Sides     []string `yaml:"sides" desc:"PCB edges with a clip: inner and outer across the columns, back and front along the column"`
+}
+
+type PCBRim struct {
+	Thickness float64 `yaml:"thickness,omitempty" desc:"Rim wall thickness, 0 disables the rim" schema:"minimum=0"`
+	Clearance float64 `yaml:"clearance,omitempty" desc:"Gap between the PCB edge and the rim or the clips" schema:"minimum=0"`
+}
+
+// PCBSides lists the PCB edges a clip can be placed at.
+var PCBSides = []string{"inner", "outer", "back", "front"}
+
+func LoadPCBDefinitions(path string) (map[string]*PCBDefinition, error) {
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read pcb definitions directory: "+path), err)
+	}
+	pcbs := make(map[string]*PCBDefinition)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".yml" {
+			continue
+		}
+		pcb, err := LoadPCBDefinition(filepath.Join(path, file.Name()))
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to load pcb definition"), err)
+		}
+		name := filepath.Base(file.Name())
+		pcbs[strings.TrimSuffix(name, filepath.Ext(name))] = &pcb
+	}
+	return pcbs, nil
+}
+
+func LoadPCBDefinition(path string) (PCBDefinition, error) {
+	pcb := PCBDefinition{Mount: PCBMount{Type: PCBMountNone}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PCBDefinition{}, errors.Join(errors.New("failed to read pcb definition file"), err)
+	}
+	err = yaml.Unmarshal(data, &pcb)
+	if err != nil {
+		return PCBDefinition{}, errors.Join(errors.New("failed to unmarshal pcb definition"), err)
+	}
+	return pcb, nil
+}
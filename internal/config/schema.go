@@ -0,0 +1,198 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaOptions задаёт значения, которые известны только по конкретному
+// конфигу или репозиторию свитчей и попадают в схему как enum.
+type SchemaOptions struct {
+	SwitchTypes    []string
+	SwitchModules  []string
+	PCBs           []string
+	RenderProfiles []string
+}
+
+type schemaBuilder struct {
+	options SchemaOptions
+	defs    map[string]any
+}
+
+// Schema строит JSON Schema (draft 2020-12) для YAML-конфига клавиатуры.
+func Schema(options SchemaOptions) map[string]any {
+	b := &schemaBuilder{options: options, defs: make(map[string]any)}
+	defaults := Default()
+	root := b.structSchema(reflect.TypeOf(defaults), reflect.ValueOf(defaults))
+	root["$schema"] = schemaDraft
+	root["title"] = "typemon keyboard config"
+	root["properties"].(map[string]any)[extendsKey] = map[string]any{
+		"description": "Config name or list of config names to inherit from",
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+	// a half takes any shared setting except the ones in halfSharedKeys
+	properties := root["properties"].(map[string]any)
+	halfProperties := make(map[string]any, len(properties))
+	for name, property := range properties {
+		if !slices.Contains(halfSharedKeys, name) {
+			halfProperties[name] = property
+		}
+	}
+	b.defs["Half"] = map[string]any{
+		"type":                 "object",
+		"properties":           halfProperties,
+		"additionalProperties": false,
+	}
+	for _, half := range Halves {
+		properties[half] = map[string]any{
+			"$ref":        "#/$defs/Half",
+			"description": "Settings of the " + half + " half deep-merged over the shared ones",
+		}
+	}
+	root["$defs"] = b.defs
+	return root
+}
+
+// SwitchModuleSchema строит JSON Schema для YAML-определений модулей свитчей.
+func SwitchModuleSchema() map[string]any {
+	b := &schemaBuilder{defs: make(map[string]any)}
+	root := b.structSchema(reflect.TypeOf(SwitchModuleDefinition{}), reflect.Value{})
+	root["$schema"] = schemaDraft
+	root["title"] = "typemon switch module definition"
+	root["required"] = []string{"filename", "module", "min_keycap_size"}
+	root["$defs"] = b.defs
+	return root
+}
+
+// PCBSchema строит JSON Schema для YAML-определений плат под клавиши.
+func PCBSchema() map[string]any {
+	b := &schemaBuilder{defs: make(map[string]any)}
+	root := b.structSchema(reflect.TypeOf(PCBDefinition{}), reflect.Value{})
+	root["$schema"] = schemaDraft
+	root["title"] = "typemon pcb definition"
+	root["required"] = []string{"outline", "thickness", "socket", "mount"}
+	root["$defs"] = b.defs
+	return root
+}
+
+func (b *schemaBuilder) typeSchema(t reflect.Type, value reflect.Value) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.typeSchema(t.Elem(), reflect.Value{})
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := b.defs[name]; !ok {
+			// reserve the name first so recursive types terminate
+			b.defs[name] = nil
+			b.defs[name] = b.structSchema(t, reflect.Value{})
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	case reflect.Map:
+		schema := map[string]any{
+			"type":                 "object",
+			"additionalProperties": b.typeSchema(t.Elem(), reflect.Value{}),
+		}
+		if t.Key().Kind() == reflect.Int {
+			schema["propertyNames"] = map[string]any{"pattern": "^[0-9]+$"}
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.typeSchema(t.Elem(), reflect.Value{})}
+	case reflect.Interface:
+		return map[string]any{}
+	}
+
+	schema := map[string]any{}
+	switch t.Kind() {
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		schema["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+	case reflect.String:
+		schema["type"] = "string"
+	}
+	if value.IsValid() && !value.IsZero() {
+		schema["default"] = value.Interface()
+	}
+	return schema
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type, value reflect.Value) map[string]any {
+	properties := make(map[string]any)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		var fieldValue reflect.Value
+		if value.IsValid() {
+			fieldValue = value.Field(i)
+		}
+		var schema map[string]any
+		if field.Type.Kind() == reflect.Struct && fieldValue.IsValid() && !fieldValue.IsZero() {
+			// nested structs with defaults are inlined so their defaults are kept
+			schema = b.structSchema(field.Type, fieldValue)
+		} else {
+			schema = b.typeSchema(field.Type, fieldValue)
+		}
+		if desc := field.Tag.Get("desc"); desc != "" {
+			schema["description"] = desc
+		}
+		b.applyConstraints(schema, field.Tag.Get("schema"))
+		properties[name] = schema
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// applyConstraints parses a `schema:"minimum=0,enum=a|b,ref=switch_type"` tag.
+func (b *schemaBuilder) applyConstraints(schema map[string]any, tag string) {
+	if tag == "" {
+		return
+	}
+	for constraint := range strings.SplitSeq(tag, ",") {
+		key, value, _ := strings.Cut(constraint, "=")
+		switch key {
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			number, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				schema[key] = number
+			}
+		case "enum":
+			schema["enum"] = strings.Split(value, "|")
+		case "ref":
+			if enum := b.referenceEnum(value); len(enum) > 0 {
+				schema["enum"] = enum
+			}
+		}
+	}
+}
+
+func (b *schemaBuilder) referenceEnum(ref string) []string {
+	var values []string
+	switch ref {
+	case "switch_type":
+		values = slices.Clone(b.options.SwitchTypes)
+	case "switch_module":
+		values = slices.Clone(b.options.SwitchModules)
+	case "pcb":
+		values = slices.Clone(b.options.PCBs)
+	case "render_profile":
+		values = slices.Clone(b.options.RenderProfiles)
+	}
+	slices.Sort(values)
+	return values
+}
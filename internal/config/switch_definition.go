@@ -10,16 +10,25 @@ import (
 )
 
 type SwitchModuleDefinition struct {
-	Filename      string                 `yaml:"filename"`
-	Module        string                 `yaml:"module"`
-	MinKeycapSize MinKeycapSize          `yaml:"min_keycap_size,omitempty"`
-	ExtraArgs     map[string]interface{} `yaml:"extra_args,omitempty"`
+	Filename      string                 `yaml:"filename" desc:"SCAD file in scad/modules/switches"`
+	Module        string                 `yaml:"module" desc:"Name of the cutout module, called as module(plane_thickness, key_size, ...extra_args)"`
+	MinKeycapSize KeycapSize             `yaml:"min_keycap_size,omitempty" desc:"Smallest keycap the switch is used with"`
+	BodySize      *SwitchBodySize        `yaml:"body_size,omitempty" desc:"Switch body below the top of the keywell plane, pins included, used by clearance checks"`
+	ExtraArgs     map[string]interface{} `yaml:"extra_args,omitempty" desc:"Extra module arguments along with their default values"`
 }
 
-type MinKeycapSize struct {
-	Width  float64 `yaml:"width,omitempty"`
-	Height float64 `yaml:"height,omitempty"`
-	Depth  float64 `yaml:"depth,omitempty"`
+// KeycapSize — габариты колпачка клавиши.
+type KeycapSize struct {
+	Width  float64 `yaml:"width,omitempty" desc:"Keycap size along the X axis" schema:"exclusiveMinimum=0"`
+	Height float64 `yaml:"height,omitempty" desc:"Keycap size along the Y axis" schema:"exclusiveMinimum=0"`
+	Depth  float64 `yaml:"depth,omitempty" desc:"Keycap height above the switch" schema:"exclusiveMinimum=0"`
+}
+
+// SwitchBodySize — габариты корпуса свитча под верхом плоскости keywell.
+type SwitchBodySize struct {
+	Width  float64 `yaml:"width" desc:"Body size along the X axis" schema:"exclusiveMinimum=0"`
+	Height float64 `yaml:"height" desc:"Body size along the Y axis" schema:"exclusiveMinimum=0"`
+	Depth  float64 `yaml:"depth" desc:"Body depth below the top of the keywell plane" schema:"exclusiveMinimum=0"`
 }
 
 func LoadSwitchModules(path string) (map[string]*SwitchModuleDefinition, error) {
@@ -0,0 +1,675 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic описывает одну проблему конфига с путём в YAML и позицией в файле.
+type Diagnostic struct {
+	Severity Severity
+	File     string
+	Path     string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Path, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) HasErrors() bool {
+	return slices.ContainsFunc(ds, func(d Diagnostic) bool { return d.Severity == SeverityError })
+}
+
+func (ds Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, d := range ds {
+		if d.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Err joins all error diagnostics into a single error, warnings are skipped.
+func (ds Diagnostics) Err() error {
+	var errs []error
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			errs = append(errs, errors.New(d.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type validator struct {
+	doc         *Document
+	diagnostics Diagnostics
+}
+
+func (v *validator) report(severity Severity, message string, path ...string) {
+	file, line, column := v.doc.Position(path...)
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		File:     file,
+		Path:     formatPath(path),
+		Line:     line,
+		Column:   column,
+		Message:  message,
+	})
+}
+
+func (v *validator) errorf(path []string, format string, args ...any) {
+	v.report(SeverityError, fmt.Sprintf(format, args...), path...)
+}
+
+func (v *validator) warnf(path []string, format string, args ...any) {
+	v.report(SeverityWarning, fmt.Sprintf(format, args...), path...)
+}
+
+func (v *validator) positive(value float64, path ...string) {
+	if value <= 0 {
+		v.errorf(path, "must be greater than 0, got %v", value)
+	}
+}
+
+func (v *validator) nonNegative(value float64, path ...string) {
+	if value < 0 {
+		v.errorf(path, "must not be negative, got %v", value)
+	}
+}
+
+func (v *validator) index(value int, size int, name string, path ...string) bool {
+	if value < 0 || value >= size {
+		v.errorf(path, "%s %d is out of range [0, %d)", name, value, size)
+		return false
+	}
+	return true
+}
+
+func (v *validator) switchType(name string, switchTypes map[string]SwitchTypeConfig, path ...string) {
+	if _, ok := switchTypes[name]; !ok {
+		v.errorf(path, "switch type %q is not declared in switch_types", name)
+	}
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Validate проверяет конфиг целиком и возвращает все найденные проблемы,
+// а не только первую. switchModules и pcbs — известные определения свитчей
+// и плат.
+func Validate(doc *Document, switchModules map[string]*SwitchModuleDefinition, pcbs map[string]*PCBDefinition) Diagnostics {
+	v := &validator{doc: doc}
+	cfg := doc.Config
+
+	v.validateUnits(cfg.Units)
+	v.validateLayout(cfg.Layout, cfg.SwitchTypes)
+	v.validateGeometry(cfg.Geometry)
+	v.validateSwitchTypes(cfg.SwitchTypes, switchModules, pcbs)
+	v.validateKeywell(cfg)
+	v.validateCurvatureExtent(cfg, switchModules)
+	v.validateThumbCluster(cfg)
+	v.validateBottom(cfg.Bottom)
+	v.validateController(cfg.Controller)
+	v.validateTrackpoint(cfg.Trackpoint.LeftSide, cfg.Layout, "left_side")
+	v.validateTrackpoint(cfg.Trackpoint.RightSide, cfg.Layout, "right_side")
+	v.validateRender(cfg.Render)
+
+	return v.diagnostics
+}
+
+// ValidateHalves проверяет конфиги обеих половин. Проблемы, найденные в
+// обеих, выводятся один раз, остальные помечаются своей половиной.
+func ValidateHalves(left *Document, right *Document, switchModules map[string]*SwitchModuleDefinition, pcbs map[string]*PCBDefinition) Diagnostics {
+	leftDiagnostics := Validate(left, switchModules, pcbs)
+	rightDiagnostics := Validate(right, switchModules, pcbs)
+	var diagnostics Diagnostics
+	for _, d := range leftDiagnostics {
+		if !slices.Contains(rightDiagnostics, d) {
+			d.Message = HalfLeft + " half: " + d.Message
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	for _, d := range rightDiagnostics {
+		if !slices.Contains(leftDiagnostics, d) {
+			d.Message = HalfRight + " half: " + d.Message
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+func (v *validator) validateUnits(units Units) {
+	if units.Length != "mm" {
+		v.errorf([]string{"units", "length"}, "only \"mm\" units are supported, got %q", units.Length)
+	}
+	if units.Angle != "deg" {
+		v.errorf([]string{"units", "angle"}, "only \"deg\" units are supported, got %q", units.Angle)
+	}
+}
+
+// ExtraKeySides lists the sides an extra key can be attached to.
+var ExtraKeySides = []string{"inner", "outer", "back", "front"}
+
+func (v *validator) validateLayout(layout Layout, switchTypes map[string]SwitchTypeConfig) {
+	if layout.Rows <= 1 {
+		v.errorf([]string{"layout", "rows"}, "rows must be greater than 1, got %d", layout.Rows)
+	}
+	if layout.Cols <= 4 {
+		v.errorf([]string{"layout", "cols"}, "cols must be greater than 4, got %d", layout.Cols)
+	}
+	for _, col := range sortedKeys(layout.ColumnRows) {
+		path := []string{"layout", "column_rows", strconv.Itoa(col)}
+		if col < 0 || col >= layout.Cols {
+			v.errorf(path, "column %d is out of range [0, %d)", col, layout.Cols)
+			continue
+		}
+		if rows := layout.ColumnRows[col]; rows < 1 || rows > layout.Rows {
+			v.errorf(path, "column rows must be in range [1, %d], got %d", layout.Rows, rows)
+		}
+	}
+	seen := make(map[KeyIndex]int)
+	for i, key := range layout.MissingKeys {
+		path := []string{"layout", "missing_keys", "[" + strconv.Itoa(i) + "]"}
+		colOk := v.index(key.Column, layout.Cols, "column", append(path, "column")...)
+		rowOk := v.index(key.Row, layout.Rows, "row", append(path, "row")...)
+		if !colOk || !rowOk {
+			continue
+		}
+		if prev, ok := seen[key]; ok {
+			v.warnf(path, "duplicates missing_keys[%d]", prev)
+		} else if key.Row >= layout.KeyRows(key.Column) {
+			v.warnf(path, "column %d has only %d rows, the key is already missing", key.Column, layout.KeyRows(key.Column))
+		}
+		seen[key] = i
+	}
+	if layout.Rows > 1 && layout.Cols > 4 {
+		for col := range layout.Cols {
+			if !layoutColumnHasKeys(layout, col) {
+				v.errorf([]string{"layout"}, "column %d has no keys left", col)
+			}
+		}
+	}
+	for i, key := range layout.ExtraKeys {
+		path := []string{"layout", "extra_keys", "[" + strconv.Itoa(i) + "]"}
+		if !layout.HasKey(key.Column, key.Row) {
+			v.errorf(path, "neighbour key at column %d row %d does not exist", key.Column, key.Row)
+		}
+		if !slices.Contains(ExtraKeySides, key.Side) {
+			v.errorf(append(path, "side"), "side must be one of %v, got %q", ExtraKeySides, key.Side)
+		}
+		v.switchType(key.SwitchType, switchTypes, append(path, "switch_type")...)
+	}
+}
+
+func layoutColumnHasKeys(layout Layout, col int) bool {
+	for row := range layout.Rows {
+		if layout.HasKey(col, row) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateGeometry(geometry GeometryConfig) {
+	v.positive(geometry.PlaneThickness, "geometry", "plane_thickness")
+	v.positive(geometry.SupportRadius, "geometry", "support_radius")
+	v.nonNegative(geometry.KeywellElevation, "geometry", "keywell_elevation")
+	v.positive(geometry.WallBaseThickness, "geometry", "wall_base_thickness")
+	v.nonNegative(geometry.WallCenterOffsetPercent, "geometry", "wall_center_offset_percent")
+	if geometry.WallCenterOffsetPercent >= 1 {
+		v.warnf([]string{"geometry", "wall_center_offset_percent"}, "is a fraction, %v moves the walls more than twice as far from the center", geometry.WallCenterOffsetPercent)
+	}
+	v.nonNegative(geometry.KeyGap, "geometry", "key_gap")
+
+	tenting := geometry.Tenting
+	if tenting.Angle < -60 || tenting.Angle > 60 {
+		v.errorf([]string{"geometry", "tenting", "angle"}, "tent angle must be in range [-60, 60], got %v", tenting.Angle)
+	}
+	switch tenting.Pivot {
+	case TentingPivotCenter, TentingPivotBack, TentingPivotFront:
+	default:
+		v.errorf([]string{"geometry", "tenting", "pivot"}, "pivot must be %q, %q or %q, got %q", TentingPivotCenter, TentingPivotBack, TentingPivotFront, tenting.Pivot)
+	}
+}
+
+func (v *validator) validateSwitchTypes(switchTypes map[string]SwitchTypeConfig, switchModules map[string]*SwitchModuleDefinition, pcbs map[string]*PCBDefinition) {
+	if len(switchTypes) == 0 {
+		v.errorf([]string{"switch_types"}, "at least one switch type must be declared")
+	}
+	names := make([]string, 0, len(switchTypes))
+	for name := range switchTypes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		switchType := switchTypes[name]
+		if switchType.Definition == "" {
+			v.errorf([]string{"switch_types", name, "definition"}, "switch type definition is required")
+			continue
+		}
+		module, ok := switchModules[switchType.Definition]
+		if !ok {
+			v.errorf([]string{"switch_types", name, "definition"}, "unknown switch module %q", switchType.Definition)
+			continue
+		}
+		for arg := range switchType.ExtraArgs {
+			if _, ok := module.ExtraArgs[arg]; !ok {
+				v.errorf([]string{"switch_types", name, "extra_args", arg}, "switch module %q has no extra argument %q", switchType.Definition, arg)
+			}
+		}
+		v.validateKeycapSize(name, switchType, module)
+		if switchType.PCB != "" {
+			pcb, ok := pcbs[switchType.PCB]
+			if !ok {
+				v.errorf([]string{"switch_types", name, "pcb"}, "unknown pcb definition %q", switchType.PCB)
+				continue
+			}
+			v.validatePCB(name, switchType.PCB, pcb, module)
+		}
+	}
+}
+
+// validatePCB checks a PCB definition where a switch type refers to it, the
+// definitions themselves have no positions to report.
+func (v *validator) validatePCB(switchType string, name string, pcb *PCBDefinition, module *SwitchModuleDefinition) {
+	path := []string{"switch_types", switchType, "pcb"}
+	invalid := func(format string, args ...any) {
+		v.errorf(path, "pcb %q: "+format, append([]any{name}, args...)...)
+	}
+	if pcb.Outline.Width <= 0 || pcb.Outline.Height <= 0 {
+		invalid("outline width and height must be greater than 0")
+	}
+	if pcb.Outline.CornerRadius*2 > min(pcb.Outline.Width, pcb.Outline.Height) {
+		invalid("outline corner_radius must not exceed half of the outline size")
+	}
+	if pcb.Thickness <= 0 {
+		invalid("thickness must be greater than 0")
+	}
+	if pcb.Standoff < 0 {
+		invalid("standoff must not be negative")
+	}
+	if pcb.Socket.Width <= 0 || pcb.Socket.Height <= 0 || pcb.Socket.Depth <= 0 {
+		invalid("socket width, height and depth must be greater than 0")
+	}
+	if pcb.Mount.Rim.Thickness < 0 || pcb.Mount.Rim.Clearance < 0 {
+		invalid("rim thickness and clearance must not be negative")
+	}
+	switch pcb.Mount.Type {
+	case PCBMountScrew:
+		screw := pcb.Mount.Screw
+		if screw.HoleDiameter <= 0 || screw.BossDiameter <= screw.HoleDiameter || screw.Depth <= 0 {
+			invalid("screw hole_diameter and depth must be greater than 0 and boss_diameter greater than hole_diameter")
+		}
+		if len(screw.Positions) == 0 {
+			invalid("screw mount needs at least one position")
+		}
+		for _, position := range screw.Positions {
+			body := module.BodySize
+			reach := screw.BossDiameter / 2
+			if body != nil && math.Abs(position.X) < body.Width/2+reach && math.Abs(position.Y) < body.Height/2+reach {
+				v.warnf(path, "pcb %q: screw boss at (%v, %v) overlaps the switch body and is cut by the switch hole", name, position.X, position.Y)
+			}
+		}
+	case PCBMountClip:
+		clip := pcb.Mount.Clip
+		if clip.Width <= 0 || clip.Thickness <= 0 || clip.Hook <= 0 {
+			invalid("clip width, thickness and hook must be greater than 0")
+		}
+		if len(clip.Sides) == 0 {
+			invalid("clip mount needs at least one side")
+		}
+		for _, side := range clip.Sides {
+			if !slices.Contains(PCBSides, side) {
+				invalid("clip side must be one of %v, got %q", PCBSides, side)
+			}
+		}
+	case PCBMountNone:
+	default:
+		invalid("mount type must be %q, %q or %q, got %q", PCBMountScrew, PCBMountClip, PCBMountNone, pcb.Mount.Type)
+	}
+}
+
+// validateKeycapSize checks the resolved keycap size, keys are spaced and
+// supported by it.
+func (v *validator) validateKeycapSize(name string, switchType SwitchTypeConfig, module *SwitchModuleDefinition) {
+	path := []string{"switch_types", name, "keycap_size"}
+	if switchType.KeycapSize == nil {
+		path = []string{"switch_types", name, "definition"}
+	}
+	size := switchType.ResolveKeycapSize(module)
+	if size.Width <= 0 || size.Height <= 0 {
+		v.errorf(path, "keycap width and height must be greater than 0, set keycap_size or min_keycap_size of switch module %q", switchType.Definition)
+		return
+	}
+	if switchType.KeycapSize == nil {
+		return
+	}
+	minSize := module.MinKeycapSize
+	if size.Width < minSize.Width || size.Height < minSize.Height || size.Depth < minSize.Depth {
+		v.warnf(path, "keycap size %vx%vx%v is smaller than min_keycap_size %vx%vx%v of switch module %q", size.Width, size.Height, size.Depth, minSize.Width, minSize.Height, minSize.Depth, switchType.Definition)
+	}
+}
+
+func (v *validator) validateCurvature(curvature Curvature, layout Layout) {
+	path := []string{"keywell", "curvature"}
+	if !slices.Contains(CurvatureProfiles, curvature.Profile) {
+		v.errorf(append(path, "profile"), "profile must be one of %v, got %q", CurvatureProfiles, curvature.Profile)
+	}
+	if curvature.Axis != CurvatureAxisVertical && curvature.Axis != CurvatureAxisHorizontal {
+		v.errorf(append(path, "axis"), "axis must be %q or %q, got %q", CurvatureAxisVertical, CurvatureAxisHorizontal, curvature.Axis)
+	}
+	v.nonNegative(curvature.VerticalDepth, append(path, "vertical_depth")...)
+	v.nonNegative(curvature.HorizontalDepth, append(path, "horizontal_depth")...)
+	if curvature.Profile != CurvatureExplicit && len(curvature.Columns) > 0 {
+		v.warnf(append(path, "columns"), "columns are only used by the %q profile", CurvatureExplicit)
+	}
+	for _, col := range sortedKeys(curvature.Columns) {
+		colPath := append(path, "columns", strconv.Itoa(col))
+		if col < 0 || col >= layout.Cols {
+			v.warnf(colPath, "column %d is outside layout.cols (%d) and is ignored", col, layout.Cols)
+			continue
+		}
+		column := curvature.Columns[col]
+		if len(column.Heights) > layout.Rows {
+			v.warnf(append(colPath, "heights"), "has %d values for %d rows, extra values are ignored", len(column.Heights), layout.Rows)
+		}
+		if len(column.Tilts) > layout.Rows {
+			v.warnf(append(colPath, "tilts"), "has %d values for %d rows, extra values are ignored", len(column.Tilts), layout.Rows)
+		}
+	}
+}
+
+func (v *validator) validateSplay(splay Splay, layout Layout) {
+	switch splay.Pivot {
+	case SplayPivotHomeRow:
+		v.index(splay.HomeRow, layout.Rows, "home row", "keywell", "splay", "home_row")
+	case SplayPivotPoint:
+	default:
+		v.errorf([]string{"keywell", "splay", "pivot"}, "pivot must be %q or %q, got %q", SplayPivotHomeRow, SplayPivotPoint, splay.Pivot)
+	}
+	for _, col := range sortedKeys(splay.Angles) {
+		path := []string{"keywell", "splay", "angles", strconv.Itoa(col)}
+		if col < 0 || col >= layout.Cols {
+			v.warnf(path, "column %d is outside layout.cols (%d) and is ignored", col, layout.Cols)
+		}
+		if angle := splay.Angles[col]; angle <= -90 || angle >= 90 {
+			v.errorf(path, "splay angle must be in range (-90, 90), got %v", angle)
+		}
+	}
+}
+
+// keywellPitch returns the key pitch along the rows (X) and across the columns
+// (Y) the same way the generator spaces the keys. ok is false when a keycap
+// size is unknown, other checks report that.
+func keywellPitch(cfg *Config, switchModules map[string]*SwitchModuleDefinition) (rowPitch float64, columnPitch float64, ok bool) {
+	var width, height float64
+	for _, name := range cfg.KeywellSwitchTypes() {
+		switchType, found := cfg.SwitchTypes[name]
+		if !found {
+			return 0, 0, false
+		}
+		module, found := switchModules[switchType.Definition]
+		if !found {
+			return 0, 0, false
+		}
+		size := switchType.ResolveKeycapSize(module)
+		width = max(width, size.Width)
+		height = max(height, size.Height)
+	}
+	return cfg.Geometry.KeyPitch(width), cfg.Geometry.KeyPitch(height), true
+}
+
+// validateCurvatureExtent checks that the keywell fits under its curvature:
+// circular and elliptical arcs end at their radius, keys beyond it have no
+// surface to sit on.
+func (v *validator) validateCurvatureExtent(cfg *Config, switchModules map[string]*SwitchModuleDefinition) {
+	keywell := cfg.Keywell
+	curvature := keywell.Curvature
+	var vertical, horizontal bool
+	switch curvature.Profile {
+	case CurvatureSpherical, CurvatureElliptical:
+		vertical, horizontal = true, true
+	case CurvatureCylindrical:
+		vertical = curvature.Axis != CurvatureAxisHorizontal
+		horizontal = !vertical
+	}
+	if !vertical && !horizontal {
+		return
+	}
+	rowPitch, columnPitch, ok := keywellPitch(cfg, switchModules)
+	if !ok || cfg.Layout.Rows <= 0 || cfg.Layout.Cols <= 0 {
+		return
+	}
+	// the grid is centered around the origin, the curvature center is
+	// moved by center_offset
+	extentX := float64(cfg.Layout.Rows-1)/2*rowPitch + math.Abs(keywell.CenterOffset.X)
+	extentY := float64(cfg.Layout.Cols-1)/2*columnPitch + math.Abs(keywell.CenterOffset.Y)
+	if vertical && keywell.VerticalRadius > 0 && extentX >= keywell.VerticalRadius {
+		v.errorf([]string{"keywell", "vertical_radius"}, "must be greater than the keywell half-length along the rows, %.2fmm, the %s curvature ends at the radius", extentX, curvature.Profile)
+	}
+	if horizontal && keywell.HorizontalRadius > 0 && extentY >= keywell.HorizontalRadius {
+		v.errorf([]string{"keywell", "horizontal_radius"}, "must be greater than the keywell half-width across the columns, %.2fmm, the %s curvature ends at the radius", extentY, curvature.Profile)
+	}
+}
+
+func (v *validator) validateKeywell(cfg *Config) {
+	keywell := cfg.Keywell
+	layout := cfg.Layout
+
+	v.positive(keywell.HorizontalRadius, "keywell", "horizontal_radius")
+	v.positive(keywell.VerticalRadius, "keywell", "vertical_radius")
+	v.nonNegative(keywell.InnerLipSize, "keywell", "inner_lip_size")
+	v.nonNegative(keywell.OuterLipSize, "keywell", "outer_lip_size")
+	v.index(keywell.IndexFingerStartColumn, layout.Cols, "index_finger_start_column", "keywell", "index_finger_start_column")
+
+	for _, row := range sortedKeys(keywell.Modifiers.Rows) {
+		if row < 0 || row >= layout.Rows {
+			v.warnf([]string{"keywell", "modifiers", "rows", strconv.Itoa(row)}, "row %d is outside layout.rows (%d) and is ignored", row, layout.Rows)
+		}
+	}
+	for _, col := range sortedKeys(keywell.Modifiers.Columns) {
+		if col < 0 || col >= layout.Cols {
+			v.warnf([]string{"keywell", "modifiers", "columns", strconv.Itoa(col)}, "column %d is outside layout.cols (%d) and is ignored", col, layout.Cols)
+		}
+	}
+
+	v.validateCurvature(keywell.Curvature, layout)
+	v.validateSplay(keywell.Splay, layout)
+
+	if _, ok := cfg.SwitchTypes[DefaultSwitchType]; !ok {
+		v.errorf([]string{"switch_types"}, "keywell keys default to switch type %q, which is not declared", DefaultSwitchType)
+	}
+
+	seen := make(map[[2]int]int)
+	for i, modifier := range keywell.Modifiers.Matrix {
+		path := []string{"keywell", "modifiers", "matrix", "[" + strconv.Itoa(i) + "]"}
+		colOk := v.index(modifier.Column, layout.Cols, "column", append(path, "column")...)
+		rowOk := v.index(modifier.Row, layout.Rows, "row", append(path, "row")...)
+		if colOk && rowOk {
+			key := [2]int{modifier.Column, modifier.Row}
+			if prev, ok := seen[key]; ok {
+				v.errorf(path, "duplicates matrix[%d] for column %d row %d", prev, modifier.Column, modifier.Row)
+			} else {
+				seen[key] = i
+			}
+		}
+		if colOk && rowOk && !layout.HasKey(modifier.Column, modifier.Row) {
+			v.warnf(path, "key at column %d row %d is missing from the layout, the modifier is ignored", modifier.Column, modifier.Row)
+		}
+		if modifier.SwitchType != "" {
+			v.switchType(modifier.SwitchType, cfg.SwitchTypes, append(path, "switch_type")...)
+		}
+	}
+}
+
+func (v *validator) validateThumbCluster(cfg *Config) {
+	thumb := cfg.ThumbCluster
+	v.index(thumb.OriginColumnIndex, cfg.Layout.Cols, "origin_column_index", "thumb_cluster", "origin_column_index")
+
+	if len(thumb.Keys) == 0 || len(thumb.Keys) > MaxThumbKeys {
+		v.errorf([]string{"thumb_cluster", "keys"}, "thumb cluster must have from 1 to %d keys, got %d", MaxThumbKeys, len(thumb.Keys))
+	}
+	indices := sortedKeys(thumb.Keys)
+	for i, idx := range indices {
+		path := []string{"thumb_cluster", "keys", strconv.Itoa(idx)}
+		if !v.index(idx, MaxThumbKeys, "thumb key", path...) {
+			continue
+		}
+		if i == 0 && idx != 0 {
+			v.errorf(path, "thumb keys must start at index 0, got %d", idx)
+		}
+		if i > 0 && idx != indices[i-1]+1 {
+			// the connector hulls to the keywell would fill the gap
+			v.errorf(path, "thumb keys %d and %d are not contiguous, the thumb plane does not support gaps", indices[i-1], idx)
+		}
+		key := thumb.Keys[idx]
+		if key.Type != "" {
+			v.switchType(key.Type, cfg.SwitchTypes, append(path, "type")...)
+		}
+	}
+}
+
+func (v *validator) validateBottom(bottom Bottom) {
+	v.positive(bottom.Thickness, "bottom", "thickness")
+	v.nonNegative(bottom.Clearance, "bottom", "clearance")
+	screws := bottom.Screws
+	if screws.Count < 0 {
+		v.errorf([]string{"bottom", "screws", "count"}, "must not be negative, got %d", screws.Count)
+	}
+	if screws.Count <= 0 {
+		return
+	}
+	v.positive(screws.HoleDiameter, "bottom", "screws", "hole_diameter")
+	v.positive(screws.ClearanceDiameter, "bottom", "screws", "clearance_diameter")
+	v.positive(screws.BossHeight, "bottom", "screws", "boss_height")
+	v.nonNegative(screws.HeadDepth, "bottom", "screws", "head_depth")
+	if screws.HeadDepth >= bottom.Thickness {
+		v.errorf([]string{"bottom", "screws", "head_depth"}, "must be less than the plate thickness %v, got %v", bottom.Thickness, screws.HeadDepth)
+	}
+	if screws.HeadDiameter < screws.ClearanceDiameter {
+		v.errorf([]string{"bottom", "screws", "head_diameter"}, "must not be less than clearance_diameter %v, got %v", screws.ClearanceDiameter, screws.HeadDiameter)
+	}
+	if screws.BossDiameter <= screws.HoleDiameter {
+		v.errorf([]string{"bottom", "screws", "boss_diameter"}, "must be greater than hole_diameter %v, got %v", screws.HoleDiameter, screws.BossDiameter)
+	}
+}
+
+// ControllerWalls lists the wall segments a controller can be placed at.
+var ControllerWalls = []string{"inner", "back", "outer"}
+
+func (v *validator) validateController(controller Controller) {
+	if !controller.Enabled {
+		return
+	}
+	path := []string{"controller"}
+	if _, ok := ControllerBoards[controller.Board]; !ok && controller.Board != ControllerCustom {
+		v.errorf(append(path, "board"), "board must be %q, %q, %q or %q, got %q", ControllerNiceNano, ControllerProMicro, ControllerRP2040Zero, ControllerCustom, controller.Board)
+	}
+	size := controller.ResolveSize()
+	sizeOk := size.Width > 0 && size.Length > 0 && size.Thickness > 0
+	if !sizeOk {
+		v.errorf(append(path, "size"), "board width, length and thickness must be greater than 0, custom boards need all of them in size")
+	}
+	if size.ComponentHeight < 0 {
+		v.errorf(append(path, "size", "component_height"), "must not be negative, got %v", size.ComponentHeight)
+	}
+	if !slices.Contains(ControllerWalls, controller.Wall) {
+		v.errorf(append(path, "wall"), "wall must be one of %v, got %q", ControllerWalls, controller.Wall)
+	}
+	if controller.Position < 0 || controller.Position > 1 {
+		v.errorf(append(path, "position"), "position must be in range [0, 1], got %v", controller.Position)
+	}
+	v.nonNegative(controller.Elevation, "controller", "elevation")
+	v.nonNegative(controller.Clearance, "controller", "clearance")
+
+	usb := controller.USB
+	v.positive(usb.Width, "controller", "usb", "width")
+	v.positive(usb.Height, "controller", "usb", "height")
+	v.nonNegative(usb.Elevation, "controller", "usb", "elevation")
+	v.nonNegative(usb.Chamfer, "controller", "usb", "chamfer")
+	if sizeOk && usb.Width > size.Width+2*controller.Clearance {
+		v.warnf(append(path, "usb", "width"), "cutout is wider than the board cavity (%v)", size.Width+2*controller.Clearance)
+	}
+
+	holder := controller.Holder
+	switch holder.Type {
+	case ControllerHolderCradle:
+		v.positive(holder.WallThickness, "controller", "holder", "wall_thickness")
+		v.nonNegative(holder.Ledge, "controller", "holder", "ledge")
+		if sizeOk && holder.Ledge*2 >= size.Width {
+			v.errorf(append(path, "holder", "ledge"), "ledge must be less than half of the board width %v, got %v", size.Width, holder.Ledge)
+		}
+	case ControllerHolderPocket:
+	default:
+		v.errorf(append(path, "holder", "type"), "holder type must be %q or %q, got %q", ControllerHolderCradle, ControllerHolderPocket, holder.Type)
+	}
+}
+
+func (v *validator) validateTrackpoint(side TrackpointSide, layout Layout, name string) {
+	if !side.Enabled {
+		return
+	}
+	path := []string{"trackpoint", name}
+	// the trackpoint needs a next column and a next row
+	v.index(side.ColumnIndex, layout.Cols-1, "column", append(path, "column_index")...)
+	v.index(side.RowIndex, layout.Rows-1, "row", append(path, "row_index")...)
+	v.positive(side.HoleDiameter, "trackpoint", name, "hole_diameter")
+	v.positive(side.MountDiameter, "trackpoint", name, "mount_diameter")
+	v.nonNegative(side.MountDepth, "trackpoint", name, "mount_depth")
+	v.nonNegative(side.Clearance, "trackpoint", name, "clearance")
+	if side.MountDepth > 0 && side.MountDiameter <= side.HoleDiameter {
+		v.errorf(append(path, "mount_diameter"), "must be greater than hole_diameter %v, got %v", side.HoleDiameter, side.MountDiameter)
+	}
+}
+
+func (v *validator) validateRender(render Render) {
+	if _, ok := render.Profiles[render.Profile]; !ok {
+		v.errorf([]string{"render", "profile"}, "unknown render profile %q", render.Profile)
+	}
+	names := make([]string, 0, len(render.Profiles))
+	for name := range render.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		profile := render.Profiles[name]
+		if profile.Fn < 0 {
+			v.errorf([]string{"render", "profiles", name, "fn"}, "must not be negative, got %d", profile.Fn)
+		}
+		if profile.Fn == 0 {
+			v.positive(profile.Fa, "render", "profiles", name, "fa")
+			v.positive(profile.Fs, "render", "profiles", name, "fs")
+		}
+	}
+}
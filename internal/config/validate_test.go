@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// validateOverride validates the default config with the YAML override on top
+// and returns the diagnostics of the given path.
+func validateOverride(t *testing.T, override string, path string) Diagnostics {
+	t.Helper()
+	base, err := filepath.Abs("../../configs/default.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "test.yml")
+	err = os.WriteFile(file, []byte("extends: "+base+"\n"+override), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules, err := LoadSwitchModules("../../configs/switches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcbs, err := LoadPCBDefinitions("../../configs/pcbs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found Diagnostics
+	for _, d := range Validate(doc, modules, pcbs) {
+		if d.Path == path {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// bigKeycap declares a switch type with a keycap twice as wide as the
+// default, bigKeycapKey puts it on the key at column 4 row 3.
+const (
+	bigKeycap    = "switch_types:\n  big:\n    definition: choc_v1\n    keycap_size:\n      width: 40\n"
+	bigKeycapKey = "  modifiers:\n    matrix:\n      - column: 4\n        row: 3\n        switch_type: big\n"
+)
+
+// TestValidate checks one rule per case: the override on top of the default
+// config must or must not produce an error at path, and every diagnostic at
+// path must be about the rule.
+func TestValidate(t *testing.T) {
+	const (
+		curvatureEnds = "curvature ends at the radius"
+		thumbGap      = "not contiguous"
+	)
+	tests := []struct {
+		name     string
+		override string
+		path     string
+		invalid  bool
+		message  string
+	}{
+		{"default radii", "", "keywell.horizontal_radius", false, curvatureEnds},
+		{"horizontal radius inside the keywell", "keywell:\n  horizontal_radius: 30\n", "keywell.horizontal_radius", true, curvatureEnds},
+		{"vertical radius inside the keywell", "keywell:\n  vertical_radius: 20\n", "keywell.vertical_radius", true, curvatureEnds},
+		{"elliptical", "keywell:\n  horizontal_radius: 30\n  curvature:\n    profile: elliptical\n", "keywell.horizontal_radius", true, curvatureEnds},
+		{"cylindrical along the other axis", "keywell:\n  horizontal_radius: 30\n  curvature:\n    profile: cylindrical\n    axis: vertical\n", "keywell.horizontal_radius", false, curvatureEnds},
+		{"parabolic has no edge", "keywell:\n  horizontal_radius: 30\n  curvature:\n    profile: parabolic\n", "keywell.horizontal_radius", false, curvatureEnds},
+		{"large keycap on a present key", bigKeycap + "keywell:\n  vertical_radius: 40\n" + bigKeycapKey, "keywell.vertical_radius", true, curvatureEnds},
+		{"large keycap on a missing key", bigKeycap + "layout:\n  missing_keys:\n    - column: 4\n      row: 3\n" +
+			"keywell:\n  vertical_radius: 40\n" + bigKeycapKey, "keywell.vertical_radius", false, curvatureEnds},
+		{"next thumb key", "thumb_cluster:\n  keys:\n    3: {}\n", "thumb_cluster.keys.3", false, thumbGap},
+		{"gap before the thumb key", "thumb_cluster:\n  keys:\n    4: {}\n", "thumb_cluster.keys.4", true, thumbGap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := validateOverride(t, tt.override, tt.path)
+			if diagnostics.HasErrors() != tt.invalid {
+				t.Errorf("errors at %s: %v, want invalid %v", tt.path, diagnostics, tt.invalid)
+			}
+			for _, d := range diagnostics {
+				if !strings.Contains(d.Message, tt.message) {
+					t.Errorf("unexpected diagnostic: %s", d)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"typemon/internal/config"
+	"typemon/internal/geometry"
+)
+
+func offsetVec(offset config.Offset) geometry.Vec3 {
+	return geometry.Vec3{offset.X, offset.Y, offset.Z}
+}
+
+func rotationVec(rotation config.Rotation) geometry.Vec3 {
+	return geometry.Vec3{rotation.X, rotation.Y, rotation.Z}
+}
+
+// keycapSize returns the keycap size of the switch type, zero when the type
+// or its size is unknown.
+func keycapSize(cfg *config.Config, switches *switchRepository, switchType string) geometry.Vec3 {
+	module, err := switches.GetModule(switchType)
+	if err != nil {
+		return geometry.Vec3{}
+	}
+	size := cfg.SwitchTypes[switchType].ResolveKeycapSize(module)
+	return geometry.Vec3{size.Width, size.Height, size.Depth}
+}
+
+// switchBody returns the switch body size of the switch type, zero when the
+// type or the body size is unknown.
+func switchBody(switches *switchRepository, switchType string) geometry.Vec3 {
+	module, err := switches.GetModule(switchType)
+	if err != nil || module.BodySize == nil {
+		return geometry.Vec3{}
+	}
+	return geometry.Vec3{module.BodySize.Width, module.BodySize.Height, module.BodySize.Depth}
+}
+
+// keyPCB returns the PCB of the switch type in key coordinates, nil when the
+// type has none.
+func keyPCB(cfg *config.Config, switches *switchRepository, switchType string) *geometry.PCB {
+	pcb := switches.GetPCB(switchType)
+	if pcb == nil {
+		return nil
+	}
+	return geometry.NewPCB(
+		cfg.Geometry.PlaneThickness,
+		pcb.Standoff,
+		geometry.Vec3{pcb.Outline.Offset.X, pcb.Outline.Offset.Y, 0},
+		geometry.Vec3{pcb.Outline.Width, pcb.Outline.Height, pcb.Thickness},
+		geometry.Vec3{pcb.Socket.Position.X, pcb.Socket.Position.Y, 0},
+		geometry.Vec3{pcb.Socket.Width, pcb.Socket.Height, pcb.Socket.Depth},
+	)
+}
+
+// newController returns nil when the controller is disabled.
+func newController(cfg *config.Config) *geometry.Controller {
+	controller := cfg.Controller
+	if !controller.Enabled {
+		return nil
+	}
+	size := controller.ResolveSize()
+	holderWall := 0.0
+	if controller.Holder.Type == config.ControllerHolderCradle {
+		holderWall = controller.Holder.WallThickness
+	}
+	return &geometry.Controller{
+		Size:            geometry.Vec3{size.Width, size.Length, size.Thickness},
+		ComponentHeight: size.ComponentHeight,
+		Wall:            geometry.Wall(controller.Wall),
+		Position:        controller.Position,
+		Floor:           cfg.Bottom.Thickness,
+		Elevation:       controller.Elevation,
+		Clearance:       controller.Clearance,
+		HolderWall:      holderWall,
+	}
+}
+
+// newTrackpoint returns nil when the trackpoint of the half is disabled.
+func newTrackpoint(trackpoint config.Trackpoint, half string) *geometry.Trackpoint {
+	side := trackpoint.LeftSide
+	if half == config.HalfRight {
+		side = trackpoint.RightSide
+	}
+	if !side.Enabled {
+		return nil
+	}
+	return &geometry.Trackpoint{
+		Column:        side.ColumnIndex,
+		Row:           side.RowIndex,
+		Offset:        offsetVec(side.Offset),
+		HoleDiameter:  side.HoleDiameter,
+		MountDiameter: side.MountDiameter,
+		MountDepth:    side.MountDepth,
+		Clearance:     side.Clearance,
+	}
+}
+
+// keyPitch spaces keys by the largest footprint along the axis. Support
+// shapes stick out of the footprint corners, so their radius is added along
+// with the configured gap.
+func keyPitch(sizes []geometry.Vec3, axis int, geometryConfig config.GeometryConfig) float64 {
+	largest := 0.0
+	for _, size := range sizes {
+		largest = max(largest, size[axis])
+	}
+	return geometryConfig.KeyPitch(largest)
+}
+
+func curvatureDepth(depth float64, radius float64) float64 {
+	if depth == 0 {
+		return radius
+	}
+	return depth
+}
+
+func newCurvature(keywell config.Keywell, numCols int) geometry.Curvature {
+	curvature := keywell.Curvature
+	vertical := keywell.VerticalRadius
+	horizontal := keywell.HorizontalRadius
+	switch curvature.Profile {
+	case config.CurvatureCylindrical:
+		if curvature.Axis == config.CurvatureAxisHorizontal {
+			return geometry.Curvature{Vertical: geometry.Flat{}, Horizontal: geometry.Circle{Radius: horizontal}}
+		}
+		return geometry.Curvature{Vertical: geometry.Circle{Radius: vertical}, Horizontal: geometry.Flat{}}
+	case config.CurvatureParabolic:
+		return geometry.Curvature{Vertical: geometry.Parabola{Radius: vertical}, Horizontal: geometry.Parabola{Radius: horizontal}}
+	case config.CurvatureElliptical:
+		return geometry.Curvature{
+			Vertical:   geometry.Ellipse{Radius: vertical, Depth: curvatureDepth(curvature.VerticalDepth, vertical)},
+			Horizontal: geometry.Ellipse{Radius: horizontal, Depth: curvatureDepth(curvature.HorizontalDepth, horizontal)},
+		}
+	case config.CurvatureExplicit:
+		columns := make([]geometry.ExplicitColumn, numCols)
+		for col, column := range curvature.Columns {
+			if col >= 0 && col < numCols {
+				columns[col] = geometry.ExplicitColumn{Heights: column.Heights, Tilts: column.Tilts, SideTilt: column.SideTilt}
+			}
+		}
+		return geometry.Curvature{Explicit: columns}
+	default:
+		return geometry.Curvature{Vertical: geometry.Circle{Radius: vertical}, Horizontal: geometry.Circle{Radius: horizontal}}
+	}
+}
+
+func newSplay(splay config.Splay, numCols int) geometry.Splay {
+	angles := make([]float64, numCols)
+	for col, angle := range splay.Angles {
+		if col >= 0 && col < numCols {
+			angles[col] = angle
+		}
+	}
+	homeRow := splay.HomeRow
+	if splay.Pivot == config.SplayPivotPoint {
+		homeRow = -1
+	}
+	return geometry.Splay{Angles: angles, HomeRow: homeRow, Point: offsetVec(splay.Point)}
+}
+
+func newLayoutParams(cfg *config.Config, half string, switches *switchRepository, keywell templateKeywell, thumb templateThumbCluster) geometry.Params {
+	keys := make([][]geometry.KeyModifier, len(keywell.Matrix))
+	for col, row := range keywell.Matrix {
+		keys[col] = make([]geometry.KeyModifier, len(row))
+		for i, key := range row {
+			keys[col][i] = geometry.KeyModifier{
+				SwitchType: key.Type,
+				Offset:     offsetVec(key.Offset),
+				Rotation:   rotationVec(key.Rotation),
+				Keycap:     keycapSize(cfg, switches, key.Type),
+				Body:       switchBody(switches, key.Type),
+				PCB:        keyPCB(cfg, switches, key.Type),
+			}
+		}
+	}
+	present := make([][]bool, cfg.Layout.Cols)
+	for col := range present {
+		present[col] = make([]bool, cfg.Layout.Rows)
+		for row := range present[col] {
+			present[col][row] = cfg.Layout.HasKey(col, row)
+		}
+	}
+	extra := make([]geometry.ExtraKey, 0, len(cfg.Layout.ExtraKeys))
+	for _, key := range cfg.Layout.ExtraKeys {
+		extra = append(extra, geometry.ExtraKey{
+			Column:     key.Column,
+			Row:        key.Row,
+			Side:       geometry.Side(key.Side),
+			SwitchType: key.SwitchType,
+			Offset:     offsetVec(key.Offset),
+			Rotation:   rotationVec(key.Rotation),
+			Keycap:     keycapSize(cfg, switches, key.SwitchType),
+			Body:       switchBody(switches, key.SwitchType),
+			PCB:        keyPCB(cfg, switches, key.SwitchType),
+		})
+	}
+	// the same keys set the pitch in the validation
+	var keySizes []geometry.Vec3
+	for _, switchType := range cfg.KeywellSwitchTypes() {
+		keySizes = append(keySizes, keycapSize(cfg, switches, switchType))
+	}
+	thumbKeys := make([]geometry.ThumbKey, 0, len(thumb.keys))
+	var thumbSizes []geometry.Vec3
+	for _, key := range thumb.Keys() {
+		thumbKeys = append(thumbKeys, geometry.ThumbKey{
+			SwitchType: key.Type,
+			Offset:     offsetVec(key.Offset),
+			Rotation:   rotationVec(key.Rotation),
+			Keycap:     keycapSize(cfg, switches, key.Type),
+			Body:       switchBody(switches, key.Type),
+			PCB:        keyPCB(cfg, switches, key.Type),
+		})
+		thumbSizes = append(thumbSizes, thumbKeys[len(thumbKeys)-1].Keycap)
+	}
+	return geometry.Params{
+		Columns:        cfg.Layout.Cols,
+		Rows:           cfg.Layout.Rows,
+		RowPitch:       keyPitch(keySizes, 0, cfg.Geometry),
+		ColumnPitch:    keyPitch(keySizes, 1, cfg.Geometry),
+		ThumbPitch:     keyPitch(thumbSizes, 1, cfg.Geometry),
+		PlaneThickness: cfg.Geometry.PlaneThickness,
+		SupportRadius:  cfg.Geometry.SupportRadius,
+		Elevation:      cfg.Geometry.KeywellElevation,
+		TiltAngle:      keywell.TiltAngle,
+		Tenting: geometry.Tenting{
+			Angle: cfg.Geometry.Tenting.Angle,
+			Pivot: geometry.TentPivot(cfg.Geometry.Tenting.Pivot),
+		},
+		InnerLipSize:      keywell.InnerLipSize,
+		OuterLipSize:      keywell.OuterLipSize,
+		WallBaseThickness: cfg.Geometry.WallBaseThickness,
+		WallCenterOffset:  cfg.Geometry.WallCenterOffsetPercent,
+		Curvature:         newCurvature(cfg.Keywell, cfg.Layout.Cols),
+		CenterOffset:      geometry.Vec3{keywell.CenterOffset.X, keywell.CenterOffset.Y, 0},
+		Keys:              keys,
+		Present:           present,
+		Extra:             extra,
+		Splay:             newSplay(cfg.Keywell.Splay, cfg.Layout.Cols),
+		Thumb: geometry.Thumb{
+			OriginColumn: thumb.OriginColumnIndex,
+			Offset:       offsetVec(thumb.Offset),
+			Rotation:     rotationVec(thumb.Rotation),
+			Keys:         thumbKeys,
+		},
+		Controller: newController(cfg),
+		Trackpoint: newTrackpoint(cfg.Trackpoint, half),
+		BottomScrews: geometry.BottomScrews{
+			Count:        cfg.Bottom.Screws.Count,
+			Clearance:    cfg.Bottom.Clearance,
+			BossDiameter: cfg.Bottom.Screws.BossDiameter,
+		},
+	}
+}
+
+// Layout computes the key transforms of one half of the configured keyboard.
+func (g *generator) Layout(half string) (*geometry.Layout, error) {
+	cfg, ok := g.configs[half]
+	if !ok {
+		return nil, errors.New("unknown half: " + half)
+	}
+	data, err := newTemplateData(cfg, half, g.switches, g.profile)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create template data"), err)
+	}
+	return data.Model, nil
+}
+
+// PlacedKeys returns the keys of one half in desk coordinates, the right half
+// mirrored as in its SCAD model.
+func (g *generator) PlacedKeys(half string) ([]geometry.PlacedKey, error) {
+	layout, err := g.Layout(half)
+	if err != nil {
+		return nil, err
+	}
+	return layout.PlacedKeys(half == config.HalfRight), nil
+}
+
+// CurvatureProfile returns the name of the keywell curvature profile of one
+// half.
+func (g *generator) CurvatureProfile(half string) string {
+	return g.configs[half].Keywell.Curvature.Profile
+}
+
+// Collisions reports every pair of intersecting keycaps of one half.
+func (g *generator) Collisions(half string) ([]geometry.Collision, error) {
+	layout, err := g.Layout(half)
+	if err != nil {
+		return nil, err
+	}
+	return layout.Collisions(), nil
+}
+
+// Interferences reports case parts of one half cutting into switches or
+// keywell supports.
+func (g *generator) Interferences(half string) ([]geometry.Interference, error) {
+	layout, err := g.Layout(half)
+	if err != nil {
+		return nil, err
+	}
+	return layout.Interferences(), nil
+}
+
+// checkLayout returns keycap collisions and part interferences of one half as
+// warnings, or fails on them in strict mode.
+func (g *generator) checkLayout(half string, layout *geometry.Layout) ([]string, error) {
+	var problems []string
+	for _, collision := range layout.Collisions() {
+		problems = append(problems, half+" half: "+collision.String())
+	}
+	for _, interference := range layout.Interferences() {
+		problems = append(problems, half+" half: "+interference.String())
+	}
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	if g.strict {
+		errs := make([]error, 0, len(problems))
+		for _, problem := range problems {
+			errs = append(errs, errors.New(problem))
+		}
+		return nil, errors.Join(errors.New("layout check failed"), errors.Join(errs...))
+	}
+	return problems, nil
+}
+
+// scadNumber fails on NaN and infinities, OpenSCAD would silently turn them
+// into broken geometry.
+func scadNumber(v float64) (string, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", fmt.Errorf("computed value %v can not be written to SCAD, check the keywell radii", v)
+	}
+	// drop floating point noise such as 1e-17 for readable output
+	if math.Abs(v) < 1e-12 {
+		return "0", nil
+	}
+	return strconv.FormatFloat(v, 'g', 12, 64), nil
+}
+
+func scadVector(v geometry.Vec3) (string, error) {
+	values := make([]string, 0, len(v))
+	for _, value := range v {
+		number, err := scadNumber(value)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, number)
+	}
+	return "[" + strings.Join(values, ", ") + "]", nil
+}
+
+func scadMatrix(m geometry.Mat4) (string, error) {
+	rows := make([]string, 0, len(m))
+	for _, row := range m {
+		values := make([]string, 0, len(row))
+		for _, v := range row {
+			number, err := scadNumber(v)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, number)
+		}
+		rows = append(rows, "["+strings.Join(values, ", ")+"]")
+	}
+	return "[" + strings.Join(rows, ", ") + "]", nil
+}
@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"math"
+	"testing"
+	"typemon/internal/geometry"
+)
+
+func TestScadNumber(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{1e-17, "0"},
+		{-27.75, "-27.75"},
+		{1.0 / 3, "0.333333333333"},
+	}
+	for _, tt := range tests {
+		got, err := scadNumber(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("scadNumber(%v) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if got, err := scadNumber(value); err == nil {
+			t.Errorf("scadNumber(%v) = %q, want an error", value, got)
+		}
+	}
+}
+
+func TestScadMatrixRejectsNaN(t *testing.T) {
+	m := geometry.Identity()
+	m[2][3] = math.NaN()
+	if got, err := scadMatrix(m); err == nil {
+		t.Errorf("scadMatrix = %q, want an error", got)
+	}
+}
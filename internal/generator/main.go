@@ -11,18 +11,28 @@ import (
 )
 
 type generator struct {
-	config   *config.Config
+	// configs holds the resolved config of each half, keyed by
+	// config.HalfLeft and config.HalfRight.
+	configs  map[string]*config.Config
 	name     string
 	switches *switchRepository
+	profile  string
+	strict   bool
+}
+
+type Options struct {
+	// Profile overrides render.profile from the config when set.
+	Profile string
+	// Strict makes keycap collisions and part interferences fatal instead
+	// of warnings.
+	Strict bool
 }
 
 const (
-	configDir       = "configs"
-	configExtension = ".yml"
-	OutDir          = "scad"
-	outExtension    = ".scad"
-	RenderDir       = "models"
-	renderExtension = ".stl"
+	configDir    = "configs"
+	OutDir       = "scad"
+	outExtension = ".scad"
+	RenderDir    = "models"
 
 	outConfigExtension = ".config"
 	outRightExtension  = ".right"
@@ -35,50 +45,136 @@ const (
 func GeneratedOutExtension() string {
 	return generatedExtension + outExtension
 }
-func GeneratedRenderExtension() string {
-	return generatedExtension + renderExtension
+func GeneratedRenderExtensions() []string {
+	extensions := make([]string, 0, len(RenderFormats))
+	for _, format := range RenderFormats {
+		extensions = append(extensions, generatedExtension+"."+format)
+	}
+	return extensions
+}
+
+// GeneratedOutConfigFilename returns the generated config file of one half,
+// each half gets its own file since it may override the shared settings.
+func GeneratedOutConfigFilename(configName string, half string) string {
+	return configName + outConfigExtension + halfExtension(half) + GeneratedOutExtension()
 }
 
-func GeneratedOutConfigFilename(configName string) string {
-	return configName + outConfigExtension + GeneratedOutExtension()
+func halfExtension(half string) string {
+	if half == config.HalfLeft {
+		return outLeftExtension
+	}
+	return outRightExtension
 }
 
 func ConfigPath(configFilename string) string {
-	return filepath.Join(configDir, configFilename+configExtension)
+	return config.ResolvePath(configDir, configFilename)
+}
+
+// Dependencies lists the inputs of the generated files: the config with every
+// config it extends, the switch module and pcb definitions and the SCAD
+// sources the generated files include. Directories stand for everything
+// inside them. A config that fails to load still lists itself, so fixing it
+// is noticed.
+func Dependencies(configFilename string) []string {
+	deps := []string{
+		SwitchModulesConfigDir,
+		PCBDefinitionsConfigDir,
+		filepath.Join(OutDir, "modules"),
+		filepath.Join(OutDir, "lib"),
+	}
+	doc, err := config.LoadDocument(ConfigPath(configFilename))
+	if err != nil {
+		return append(deps, ConfigPath(configFilename))
+	}
+	return append(deps, doc.Files...)
+}
+
+// Validate loads the config and runs every validation check against it.
+func Validate(configFilename string) (config.Diagnostics, error) {
+	_, _, _, diagnostics, err := load(configFilename)
+	return diagnostics, err
 }
 
-func New(configFilename string) (*generator, error) {
-	path := ConfigPath(configFilename)
-	config, err := config.Load(path)
+// load returns the shared config document, the documents of both halves
+// keyed by half and the diagnostics of both halves.
+func load(configFilename string) (*config.Document, map[string]*config.Document, *switchRepository, config.Diagnostics, error) {
+	doc, err := config.LoadDocument(ConfigPath(configFilename))
 	if err != nil {
-		return nil, errors.Join(errors.New("failed to load config"), err)
+		return nil, nil, nil, nil, errors.Join(errors.New("failed to load config"), err)
+	}
+	halves := make(map[string]*config.Document, len(config.Halves))
+	for _, half := range config.Halves {
+		halves[half], err = doc.Half(half)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Join(errors.New("failed to load "+half+" half"), err)
+		}
 	}
 	switches, err := loadSwitchRepository()
 	if err != nil {
-		return nil, errors.Join(errors.New("failed to load switches"), err)
+		return nil, nil, nil, nil, errors.Join(errors.New("failed to load switches"), err)
+	}
+	diagnostics := config.ValidateHalves(halves[config.HalfLeft], halves[config.HalfRight], switches.modules, switches.pcbs)
+	return doc, halves, switches, diagnostics, nil
+}
+
+// New loads and validates the config. The returned diagnostics are the
+// config warnings, the caller decides where to print them.
+func New(configFilename string, opts Options) (*generator, config.Diagnostics, error) {
+	doc, halves, switches, diagnostics, err := load(configFilename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if diagnostics.HasErrors() {
+		return nil, nil, errors.Join(errors.New("invalid config"), diagnostics.Err())
+	}
+	profile := doc.Config.Render.Profile
+	if opts.Profile != "" {
+		profile = opts.Profile
+	}
+	if _, ok := doc.Config.Render.Profiles[profile]; !ok {
+		return nil, nil, errors.New("unknown render profile: " + profile)
+	}
+	configs := make(map[string]*config.Config, len(halves))
+	for half, halfDoc := range halves {
+		configs[half] = halfDoc.Config
 	}
 	return &generator{
 		name:     configFilename,
-		config:   config,
+		configs:  configs,
 		switches: switches,
-	}, nil
+		profile:  profile,
+		strict:   opts.Strict,
+	}, diagnostics, nil
 }
 
-func (g *generator) Generate() error {
-	err := g.generateConfigFile()
-	if err != nil {
-		return errors.Join(errors.New("failed to generate config file"), err)
+// Generate writes the SCAD files of both halves and returns the layout
+// warnings, keycap collisions and parts cutting into keys.
+func (g *generator) Generate() ([]string, error) {
+	var warnings []string
+	for _, half := range config.Halves {
+		halfWarnings, err := g.generateConfigFile(half)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to generate "+half+" config file"), err)
+		}
+		warnings = append(warnings, halfWarnings...)
 	}
-	err = g.generateLeftFile()
+	err := g.generateLeftFile()
 	if err != nil {
-		return errors.Join(errors.New("failed to generate left file"), err)
+		return nil, errors.Join(errors.New("failed to generate left file"), err)
 	}
 	err = g.generateRightFile()
 	if err != nil {
-		return errors.Join(errors.New("failed to generate right file"), err)
+		return nil, errors.Join(errors.New("failed to generate right file"), err)
 	}
-	// g.generateBottomFile()
-	return nil
+	err = g.generateBottomFile(true)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to generate left bottom file"), err)
+	}
+	err = g.generateBottomFile(false)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to generate right bottom file"), err)
+	}
+	return warnings, nil
 }
 
 //go:embed templates/config.scad.tmpl
@@ -90,28 +186,36 @@ var leftTemplate string
 //go:embed templates/right.scad.tmpl
 var rightTemplate string
 
-func (g *generator) generateConfigFile() error {
+//go:embed templates/bottom.scad.tmpl
+var bottomTemplate string
+
+func (g *generator) generateConfigFile(half string) ([]string, error) {
 	// generate config scad file from template
 	tmpl, err := template.New("config").Funcs(funcMap).Parse(configTemplate)
 	if err != nil {
-		return errors.Join(errors.New("failed to parse config template"), err)
+		return nil, errors.Join(errors.New("failed to parse config template"), err)
 	}
 
-	path := filepath.Join(OutDir, GeneratedOutConfigFilename(g.name))
-	file, err := os.Create(path)
+	data, err := newTemplateData(g.configs[half], half, g.switches, g.profile)
 	if err != nil {
-		return errors.Join(errors.New("failed to create config file"), err)
+		return nil, errors.Join(errors.New("failed to create template data"), err)
 	}
-	defer file.Close()
-	data, err := newTemplateData(g.config, g.switches)
+	warnings, err := g.checkLayout(half, data.Model)
 	if err != nil {
-		return errors.Join(errors.New("failed to create template data"), err)
+		return nil, err
 	}
+
+	path := filepath.Join(OutDir, GeneratedOutConfigFilename(g.name, half))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create config file"), err)
+	}
+	defer file.Close()
 	err = tmpl.Execute(file, data)
 	if err != nil {
-		return errors.Join(errors.New("failed to execute config template"), err)
+		return nil, errors.Join(errors.New("failed to execute config template"), err)
 	}
-	return nil
+	return warnings, nil
 }
 
 func (g *generator) generateLeftFile() error {
@@ -119,13 +223,13 @@ func (g *generator) generateLeftFile() error {
 	if err != nil {
 		return errors.Join(errors.New("failed to parse left template"), err)
 	}
-	path := filepath.Join(OutDir, g.name+outLeftExtension+GeneratedOutExtension())
+	path := filepath.Join(OutDir, g.leftFilename())
 	file, err := os.Create(path)
 	if err != nil {
 		return errors.Join(errors.New("failed to create left file"), err)
 	}
 	defer file.Close()
-	err = tmpl.Execute(file, GeneratedOutConfigFilename(g.name))
+	err = tmpl.Execute(file, GeneratedOutConfigFilename(g.name, config.HalfLeft))
 	if err != nil {
 		return errors.Join(errors.New("failed to execute left template"), err)
 	}
@@ -137,19 +241,76 @@ func (g *generator) generateRightFile() error {
 	if err != nil {
 		return errors.Join(errors.New("failed to parse right template"), err)
 	}
-	path := filepath.Join(OutDir, g.name+outRightExtension+GeneratedOutExtension())
+	path := filepath.Join(OutDir, g.rightFilename())
 	file, err := os.Create(path)
 	if err != nil {
 		return errors.Join(errors.New("failed to create right file"), err)
 	}
 	defer file.Close()
-	err = tmpl.Execute(file, GeneratedOutConfigFilename(g.name))
+	err = tmpl.Execute(file, GeneratedOutConfigFilename(g.name, config.HalfRight))
 	if err != nil {
 		return errors.Join(errors.New("failed to execute right template"), err)
 	}
 	return nil
 }
 
-func (g *generator) Render() error {
+type bottomTemplateData struct {
+	Config string
+	Left   bool
+}
+
+func (g *generator) generateBottomFile(left bool) error {
+	tmpl, err := template.New("bottom").Parse(bottomTemplate)
+	if err != nil {
+		return errors.Join(errors.New("failed to parse bottom template"), err)
+	}
+	path := filepath.Join(OutDir, g.bottomFilename(left))
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Join(errors.New("failed to create bottom file"), err)
+	}
+	defer file.Close()
+	err = tmpl.Execute(file, bottomTemplateData{Config: GeneratedOutConfigFilename(g.name, bottomHalf(left)), Left: left})
+	if err != nil {
+		return errors.Join(errors.New("failed to execute bottom template"), err)
+	}
 	return nil
 }
+
+type output struct {
+	name     string
+	filename string
+}
+
+func (g *generator) leftFilename() string {
+	return g.name + outLeftExtension + GeneratedOutExtension()
+}
+
+func (g *generator) rightFilename() string {
+	return g.name + outRightExtension + GeneratedOutExtension()
+}
+
+func bottomHalf(left bool) string {
+	if left {
+		return config.HalfLeft
+	}
+	return config.HalfRight
+}
+
+func (g *generator) bottomFilename(left bool) string {
+	side := outRightExtension
+	if left {
+		side = outLeftExtension
+	}
+	return g.name + outBottomExtension + side + GeneratedOutExtension()
+}
+
+// outputs lists the generated entry point files, one per printable part.
+func (g *generator) outputs() []output {
+	return []output{
+		{name: "left", filename: g.leftFilename()},
+		{name: "right", filename: g.rightFilename()},
+		{name: "left bottom", filename: g.bottomFilename(true)},
+		{name: "right bottom", filename: g.bottomFilename(false)},
+	}
+}
@@ -0,0 +1,336 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultOpenSCAD      = "openscad"
+	DefaultRenderTimeout = 30 * time.Minute
+	DefaultRenderFormat  = "stl"
+)
+
+// RenderFormats lists the output formats OpenSCAD can export the model to.
+var RenderFormats = []string{"stl", "3mf", "off"}
+
+type RenderOptions struct {
+	// OpenSCAD is the OpenSCAD binary name or path.
+	OpenSCAD string
+	// Timeout limits a single OpenSCAD job, zero means no limit.
+	Timeout time.Duration
+	// Format is one of RenderFormats.
+	Format string
+	// NoCache forces OpenSCAD to run even when a cached render matches.
+	NoCache bool
+}
+
+type renderJob struct {
+	name   string
+	input  string
+	output string
+}
+
+// RenderError describes a failed OpenSCAD job.
+type RenderError struct {
+	Job      string
+	Messages []string
+	Err      error
+}
+
+func (e *RenderError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "render %s failed", e.Job)
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %s", e.Err.Error())
+	}
+	for _, message := range e.Messages {
+		b.WriteString("\n  ")
+		b.WriteString(strings.ReplaceAll(message, "\n", "\n  "))
+	}
+	return b.String()
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+func (g *generator) renderJobs(format string) []renderJob {
+	jobs := make([]renderJob, 0, len(g.outputs()))
+	for _, out := range g.outputs() {
+		jobs = append(jobs, renderJob{
+			name:   out.name,
+			input:  filepath.Join(OutDir, out.filename),
+			output: filepath.Join(RenderDir, strings.TrimSuffix(out.filename, outExtension)+"."+format),
+		})
+	}
+	return jobs
+}
+
+// renderSetup holds what every job of a render needs.
+type renderSetup struct {
+	binary  string
+	timeout time.Duration
+	// key addresses the renders, cache tells whether they are cached.
+	key   renderCacheKey
+	cache bool
+}
+
+// setupRender checks the options, finds the OpenSCAD binary and creates the
+// render directory.
+func (g *generator) setupRender(ctx context.Context, opts RenderOptions) (*renderSetup, error) {
+	if opts.OpenSCAD == "" {
+		opts.OpenSCAD = DefaultOpenSCAD
+	}
+	if opts.Format == "" {
+		opts.Format = DefaultRenderFormat
+	}
+	if !slices.Contains(RenderFormats, opts.Format) {
+		return nil, fmt.Errorf("unsupported render format %q, expected one of %s", opts.Format, strings.Join(RenderFormats, ", "))
+	}
+	binary, err := exec.LookPath(opts.OpenSCAD)
+	if err != nil {
+		return nil, errors.Join(errors.New("openscad binary not found: "+opts.OpenSCAD), err)
+	}
+	err = os.MkdirAll(RenderDir, 0o755)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create render directory"), err)
+	}
+	setup := &renderSetup{
+		binary:  binary,
+		timeout: opts.Timeout,
+		key:     renderCacheKey{profile: g.profile, format: opts.Format},
+		cache:   !opts.NoCache,
+	}
+	if setup.cache {
+		setup.key.version, err = openSCADVersion(ctx, binary)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return setup, nil
+}
+
+// Render exports every generated half with OpenSCAD in parallel. The SCAD
+// files must be generated beforehand.
+func (g *generator) Render(ctx context.Context, opts RenderOptions) error {
+	setup, err := g.setupRender(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return setup.run(ctx, g.renderJobs(setup.key.format), nil)
+}
+
+// renderProgress numbers the finished jobs of a render.
+type renderProgress struct {
+	mu    sync.Mutex
+	done  int
+	total int
+}
+
+func (p *renderProgress) report(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	fmt.Printf("[%d/%d] %s\n", p.done, p.total, fmt.Sprintf(format, args...))
+}
+
+// run renders the jobs in parallel and reports every job when it finishes.
+// rendered is called for every job that succeeded, it may be nil.
+func (s *renderSetup) run(ctx context.Context, jobs []renderJob, rendered func(job renderJob)) error {
+	progress := &renderProgress{total: len(jobs)}
+	errs := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fmt.Printf("rendering %s\n", job.name)
+			start := time.Now()
+			cached, err := s.renderCached(ctx, job)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			switch {
+			case errors.Is(err, context.Canceled):
+				progress.report("cancelled %s after %s", job.name, elapsed)
+			case err != nil:
+				progress.report("failed to render %s after %s", job.name, elapsed)
+			case cached:
+				progress.report("restored %s from cache to %s", job.name, job.output)
+			default:
+				progress.report("rendered %s to %s in %s", job.name, job.output, elapsed)
+			}
+			if err == nil && rendered != nil {
+				rendered(job)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// renderCached runs the job unless the cache already holds its result and
+// reports whether the result came from the cache.
+func (s *renderSetup) renderCached(ctx context.Context, job renderJob) (bool, error) {
+	if !s.cache {
+		return false, runOpenSCAD(ctx, s.binary, s.timeout, job)
+	}
+	hash, err := s.key.hash(job.input)
+	if err != nil {
+		return false, &RenderError{Job: job.name, Err: err}
+	}
+	hit, err := restoreFromCache(hash, s.key.format, job.output)
+	if err != nil {
+		return false, &RenderError{Job: job.name, Err: err}
+	}
+	if hit {
+		return true, nil
+	}
+	err = runOpenSCAD(ctx, s.binary, s.timeout, job)
+	if err != nil {
+		return false, err
+	}
+	return false, storeInCache(hash, s.key.format, job.output)
+}
+
+func runOpenSCAD(ctx context.Context, binary string, timeout time.Duration, job renderJob) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, "-o", job.output, job.input)
+	cmd.Stderr = &stderr
+	// do not hang on children of a killed process that still hold stderr
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+
+	diagnostics := parseOpenSCADOutput(stderr.Bytes())
+	var messages []string
+	for _, d := range diagnostics {
+		if d.level == "ERROR" {
+			messages = append(messages, d.String())
+		} else if d.level == "WARNING" {
+			fmt.Printf("%s: %s\n", job.name, d.String())
+		}
+	}
+
+	if ctx.Err() != nil && len(messages) == 0 {
+		// the output so far tells how far OpenSCAD got
+		messages = lastLines(stderr.String(), 10)
+	}
+	switch {
+	case timeout > 0 && ctx.Err() == context.DeadlineExceeded:
+		return &RenderError{Job: job.name, Messages: messages, Err: fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())}
+	case ctx.Err() != nil:
+		return &RenderError{Job: job.name, Messages: messages, Err: ctx.Err()}
+	case err != nil:
+		if len(messages) == 0 {
+			messages = lastLines(stderr.String(), 10)
+		}
+		return &RenderError{Job: job.name, Messages: messages, Err: err}
+	case len(messages) > 0:
+		return &RenderError{Job: job.name, Messages: messages}
+	}
+	return nil
+}
+
+// openscadLocation matches the source location OpenSCAD appends to its
+// messages, e.g. `in file "../scad/x.scad", line 12` or `in file x.scad, line 12`.
+var openscadLocation = regexp.MustCompile(`,?\s*in file "?([^",]+?)"?, line (\d+)`)
+
+var openscadLevel = regexp.MustCompile(`^(ERROR|WARNING|TRACE|DEPRECATED):\s*(.*)$`)
+
+type openscadDiagnostic struct {
+	level   string
+	message string
+	file    string
+	line    int
+}
+
+func (d openscadDiagnostic) String() string {
+	if d.file == "" {
+		return d.message
+	}
+	location := fmt.Sprintf("%s:%d: %s", d.file, d.line, d.message)
+	if source := sourceLine(d.file, d.line); source != "" {
+		location += fmt.Sprintf("\n%6d | %s", d.line, source)
+	}
+	return location
+}
+
+func parseOpenSCADOutput(output []byte) []openscadDiagnostic {
+	var diagnostics []openscadDiagnostic
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		match := openscadLevel.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		d := openscadDiagnostic{level: match[1], message: match[2]}
+		if location := openscadLocation.FindStringSubmatch(d.message); location != nil {
+			d.file = relativeToWorkdir(location[1])
+			d.line, _ = strconv.Atoi(location[2])
+			d.message = strings.TrimSpace(openscadLocation.ReplaceAllString(d.message, ""))
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// relativeToWorkdir maps OpenSCAD file paths, which may be absolute or
+// relative to the rendered file, back to a path relative to the working
+// directory, e.g. scad/default.config.g.scad.
+func relativeToWorkdir(path string) string {
+	if !filepath.IsAbs(path) {
+		if _, err := os.Stat(path); err != nil {
+			path = filepath.Join(OutDir, path)
+		}
+		return filepath.Clean(path)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+func sourceLine(path string, line int) string {
+	data, err := os.ReadFile(path)
+	if err != nil || line <= 0 {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	if line > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[line-1], "\r")
+}
+
+func lastLines(text string, n int) []string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
@@ -0,0 +1,195 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const RenderCacheDir = ".cache/render"
+
+var scadIncludePattern = regexp.MustCompile(`(?m)^\s*(?:include|use)\s*<([^>]+)>`)
+
+// scadDependencies returns the file and every file it transitively includes
+// or uses, sorted. Includes are resolved relative to the including file and
+// then to OutDir, the same way OpenSCAD resolves its library path.
+func scadDependencies(path string) ([]string, error) {
+	seen := make(map[string]bool)
+	var visit func(path string) error
+	visit = func(path string) error {
+		path = filepath.Clean(path)
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Join(errors.New("failed to read scad dependency: "+path), err)
+		}
+		for _, match := range scadIncludePattern.FindAllSubmatch(data, -1) {
+			name := string(match[1])
+			candidates := []string{filepath.Join(filepath.Dir(path), name), filepath.Join(OutDir, name)}
+			found := false
+			for _, candidate := range candidates {
+				if _, err := os.Stat(candidate); err == nil {
+					found = true
+					if err := visit(candidate); err != nil {
+						return err
+					}
+					break
+				}
+			}
+			if !found {
+				return errors.New("scad dependency not found: " + name + " included from " + path)
+			}
+		}
+		return nil
+	}
+	if err := visit(path); err != nil {
+		return nil, err
+	}
+	deps := make([]string, 0, len(seen))
+	for dep := range seen {
+		deps = append(deps, dep)
+	}
+	slices.Sort(deps)
+	return deps, nil
+}
+
+type renderCacheKey struct {
+	version string
+	profile string
+	format  string
+}
+
+// hash addresses a render by the content of the input file and everything it
+// includes, plus the OpenSCAD version, render profile and output format.
+func (k renderCacheKey) hash(input string) (string, error) {
+	deps, err := scadDependencies(input)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range []string{k.version, k.profile, k.format} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, dep := range deps {
+		data, err := os.ReadFile(dep)
+		if err != nil {
+			return "", errors.Join(errors.New("failed to read scad dependency: "+dep), err)
+		}
+		h.Write([]byte(filepath.ToSlash(dep)))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func openSCADVersion(ctx context.Context, binary string) (string, error) {
+	out, err := exec.CommandContext(ctx, binary, "--version").CombinedOutput()
+	if err != nil {
+		return "", errors.Join(errors.New("failed to get openscad version"), err)
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+func renderCachePath(hash string, format string) string {
+	return filepath.Join(RenderCacheDir, hash+"."+format)
+}
+
+// restoreFromCache copies a cached render to output and reports whether the
+// cache had it.
+func restoreFromCache(hash string, format string, output string) (bool, error) {
+	path := renderCachePath(hash, format)
+	if _, err := os.Stat(path); err != nil {
+		return false, nil
+	}
+	err := copyFile(path, output)
+	if err != nil {
+		return false, errors.Join(errors.New("failed to restore cached render"), err)
+	}
+	// mtime marks the last use of a cache entry for pruning
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return true, nil
+}
+
+func storeInCache(hash string, format string, output string) error {
+	err := os.MkdirAll(RenderCacheDir, 0o755)
+	if err != nil {
+		return errors.Join(errors.New("failed to create render cache directory"), err)
+	}
+	err = copyFile(output, renderCachePath(hash, format))
+	if err != nil {
+		return errors.Join(errors.New("failed to store render in cache"), err)
+	}
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// PruneRenderCache removes cached renders not used for longer than maxAge,
+// a zero maxAge removes everything. It returns the number of removed entries
+// and the bytes freed.
+func PruneRenderCache(maxAge time.Duration) (int, int64, error) {
+	entries, err := os.ReadDir(RenderCacheDir)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, errors.Join(errors.New("failed to read render cache directory"), err)
+	}
+	removed := 0
+	var freed int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return removed, freed, errors.Join(errors.New("failed to stat cache entry"), err)
+		}
+		if maxAge > 0 && time.Since(info.ModTime()) < maxAge && !strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		err = os.Remove(filepath.Join(RenderCacheDir, entry.Name()))
+		if err != nil {
+			return removed, freed, errors.Join(errors.New("failed to remove cache entry"), err)
+		}
+		removed++
+		freed += info.Size()
+	}
+	return removed, freed, nil
+}
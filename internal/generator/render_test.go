@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeOpenSCAD writes a shell script standing in for the openscad binary and
+// moves into a working directory with a scad/test.scad to render.
+func fakeOpenSCAD(t *testing.T, script string) (string, renderJob) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake openscad is a shell script")
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "openscad")
+	err := os.WriteFile(binary, []byte("#!/bin/sh\n"+script+"\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workdir := filepath.Join(dir, "work")
+	err = os.MkdirAll(filepath.Join(workdir, OutDir, "modules"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(workdir)
+	input := filepath.Join(OutDir, "test.scad")
+	err = os.WriteFile(input, []byte("include <modules/thing.scad>\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binary, renderJob{name: "test", input: input, output: filepath.Join(RenderDir, "test.stl")}
+}
+
+func renderWith(ctx context.Context, t *testing.T, binary string, timeout time.Duration, job renderJob) error {
+	t.Helper()
+	setup, err := (&generator{}).setupRender(ctx, RenderOptions{OpenSCAD: binary, Timeout: timeout, NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return setup.run(ctx, []renderJob{job}, nil)
+}
+
+func TestRenderWritesOutput(t *testing.T) {
+	binary, job := fakeOpenSCAD(t, `[ "$1" = "-o" ] && [ "$3" = "scad/test.scad" ] || exit 2
+echo "solid test" > "$2"`)
+	err := renderWith(t.Context(), t, binary, 0, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(job.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "solid test\n" {
+		t.Errorf("output = %q, want the fake STL", data)
+	}
+}
+
+func TestRenderReportsErrorLocation(t *testing.T) {
+	binary, job := fakeOpenSCAD(t, `echo "WARNING: Ignoring unknown variable 'x' in file test.scad, line 1" >&2
+echo "ERROR: Assertion 'false' failed in file modules/thing.scad, line 2" >&2
+echo "ERROR: Parser error in file \"$PWD/scad/test.scad\", line 1: syntax error" >&2
+exit 1`)
+	err := os.WriteFile(filepath.Join(OutDir, "modules", "thing.scad"), []byte("a = 1;\nassert(false);\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = renderWith(t.Context(), t, binary, 0, job)
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("err = %v, want a RenderError", err)
+	}
+	want := []string{
+		"scad/modules/thing.scad:2: Assertion 'false' failed\n     2 | assert(false);",
+		"scad/test.scad:1: Parser error: syntax error\n     1 | include <modules/thing.scad>",
+	}
+	if strings.Join(renderErr.Messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("messages = %q, want %q", renderErr.Messages, want)
+	}
+}
+
+func TestRenderNonZeroExit(t *testing.T) {
+	binary, job := fakeOpenSCAD(t, `echo "Current top level object is empty." >&2
+exit 3`)
+	err := renderWith(t.Context(), t, binary, 0, job)
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("err = %v, want a RenderError", err)
+	}
+	if !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("err = %q, want the exit status", err)
+	}
+	if len(renderErr.Messages) != 1 || renderErr.Messages[0] != "Current top level object is empty." {
+		t.Errorf("messages = %q, want the last stderr lines", renderErr.Messages)
+	}
+}
+
+func TestRenderTimeout(t *testing.T) {
+	binary, job := fakeOpenSCAD(t, `echo "Rendering Polygon Mesh using CGAL..." >&2
+exec sleep 10`)
+	start := time.Now()
+	err := renderWith(t.Context(), t, binary, 200*time.Millisecond, job)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("render took %s, the timeout did not kill openscad", elapsed)
+	}
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a RenderError for the deadline", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("err = %q, want the timeout", err)
+	}
+	if len(renderErr.Messages) != 1 || renderErr.Messages[0] != "Rendering Polygon Mesh using CGAL..." {
+		t.Errorf("messages = %q, want the stderr collected before the timeout", renderErr.Messages)
+	}
+}
+
+func TestRenderCancelKeepsDiagnostics(t *testing.T) {
+	binary, job := fakeOpenSCAD(t, `echo "ERROR: Assertion 'false' failed in file test.scad, line 1" >&2
+exec sleep 10`)
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	err := runOpenSCAD(ctx, binary, time.Minute, job)
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want a RenderError for the cancel", err)
+	}
+	if len(renderErr.Messages) != 1 || !strings.HasPrefix(renderErr.Messages[0], "scad/test.scad:1: Assertion 'false' failed") {
+		t.Errorf("messages = %q, want the diagnostics collected before the cancel", renderErr.Messages)
+	}
+}
@@ -10,12 +10,16 @@ import (
 
 type switchRepository struct {
 	modules map[string]*config.SwitchModuleDefinition
+	// pcbs are keyed by definition name when loaded and by switch type name
+	// once resolved for a config, like modules.
+	pcbs map[string]*config.PCBDefinition
 }
 
 const (
-	SwitchModulesConfigDir = "configs/switches"
-	switchModulesDir       = "modules/switches"
-	switchModulesExtension = ".scad"
+	SwitchModulesConfigDir  = "configs/switches"
+	PCBDefinitionsConfigDir = "configs/pcbs"
+	switchModulesDir        = "modules/switches"
+	switchModulesExtension  = ".scad"
 )
 
 func loadSwitchRepository() (*switchRepository, error) {
@@ -23,7 +27,11 @@ func loadSwitchRepository() (*switchRepository, error) {
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to load switch modules"), err)
 	}
-	repo := &switchRepository{modules: make(map[string]*config.SwitchModuleDefinition)}
+	pcbs, err := config.LoadPCBDefinitions(PCBDefinitionsConfigDir)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to load pcb definitions"), err)
+	}
+	repo := &switchRepository{modules: make(map[string]*config.SwitchModuleDefinition), pcbs: pcbs}
 	for name, module := range modules {
 		err = repo.AddModule(name, module)
 		if err != nil {
@@ -33,6 +41,11 @@ func loadSwitchRepository() (*switchRepository, error) {
 	return repo, nil
 }
 
+// GetPCB returns the PCB definition, nil when there is none.
+func (r *switchRepository) GetPCB(name string) *config.PCBDefinition {
+	return r.pcbs[name]
+}
+
 func (r *switchRepository) GetModule(name string) (*config.SwitchModuleDefinition, error) {
 	module, ok := r.modules[name]
 	if !ok {
@@ -62,6 +75,7 @@ func OverrideModule(module *config.SwitchModuleDefinition, extraArgs map[string]
 		Filename:      module.Filename,
 		Module:        module.Module,
 		MinKeycapSize: module.MinKeycapSize,
+		BodySize:      module.BodySize,
 		ExtraArgs:     make(map[string]interface{}),
 	}
 	for key, value := range extraArgs {
@@ -9,6 +9,7 @@ import (
 	"text/template"
 	"typemon/internal/config"
 	"typemon/internal/generator/utils"
+	"typemon/internal/geometry"
 )
 
 type templateKeywell struct {
@@ -28,70 +29,114 @@ type keyModifier struct {
 	Type     string
 }
 
-func MatrixModifier(modifiers config.KeywellModifiers, numRows int, numCols int, indexFingerStartColumn int) [][]keyModifier {
-	matrix := make([][]keyModifier, numCols)
-	for col := range numCols {
-		matrix[col] = make([]keyModifier, numRows)
+type matrixIndex struct {
+	col int
+	row int
+}
 
-		// get finger modifier
-		currFingerIdx := 0
+func fingerModifier(modifiers config.FingerModifiers, col int, indexFingerStartColumn int) config.FingerModifier {
+	// columns before the index finger start column are also pressed by the index finger
+	currFingerIdx := max(col-indexFingerStartColumn, 0)
+	switch min(currFingerIdx, 3) {
+	case 1:
+		return modifiers.Middle
+	case 2:
+		return modifiers.Ring
+	case 3:
+		return modifiers.Pinky
+	default:
+		return modifiers.Index
+	}
+}
 
-		if col >= indexFingerStartColumn {
-			currFingerIdx = indexFingerStartColumn - col
+func matrixOverrides(modifiers []config.MatrixModifier, numRows int, numCols int) (map[matrixIndex]config.MatrixModifier, error) {
+	overrides := make(map[matrixIndex]config.MatrixModifier, len(modifiers))
+	var errs []error
+	for i, modifier := range modifiers {
+		if modifier.Column < 0 || modifier.Column >= numCols {
+			errs = append(errs, fmt.Errorf("matrix modifier %d: column %d is out of range [0, %d)", i, modifier.Column, numCols))
+			continue
 		}
-		if currFingerIdx > 3 {
-			currFingerIdx = 3
+		if modifier.Row < 0 || modifier.Row >= numRows {
+			errs = append(errs, fmt.Errorf("matrix modifier %d: row %d is out of range [0, %d)", i, modifier.Row, numRows))
+			continue
 		}
-
-		var fingerModifier config.FingerModifier
-		switch currFingerIdx {
-		case 0:
-			fingerModifier = modifiers.Finger.Index
-		case 1:
-			fingerModifier = modifiers.Finger.Middle
-		case 2:
-			fingerModifier = modifiers.Finger.Ring
-		case 3:
-			fingerModifier = modifiers.Finger.Pinky
+		idx := matrixIndex{col: modifier.Column, row: modifier.Row}
+		if _, ok := overrides[idx]; ok {
+			errs = append(errs, fmt.Errorf("matrix modifier %d: duplicate modifier for column %d row %d", i, modifier.Column, modifier.Row))
+			continue
 		}
+		overrides[idx] = modifier
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return overrides, nil
+}
 
-		var currColumnModifier *config.RowColumnModifier
-		if colModifiers, ok := modifiers.Columns[col]; ok {
-			currColumnModifier = &colModifiers
-		}
+// MatrixModifier folds finger, column, row and per-key matrix modifiers into
+// the final per-key offset, rotation and switch type. The matrix modifiers are
+// applied last and may opt out of any of the other layers.
+func MatrixModifier(modifiers config.KeywellModifiers, numRows int, numCols int, indexFingerStartColumn int) ([][]keyModifier, error) {
+	overrides, err := matrixOverrides(modifiers.Matrix, numRows, numCols)
+	if err != nil {
+		return nil, err
+	}
+	matrix := make([][]keyModifier, numCols)
+	for col := range numCols {
+		matrix[col] = make([]keyModifier, numRows)
 
-		colModifier := &keyModifier{
-			Offset:   fingerModifier.Offset,
-			Rotation: config.Rotation{X: fingerModifier.Tilt, Y: 0, Z: 0},
-			Type:     "regular",
-		}
+		finger := fingerModifier(modifiers.Finger, col, indexFingerStartColumn)
+		fingerOffset := finger.Offset
+		fingerRotation := config.Rotation{X: finger.Tilt, Y: 0, Z: 0}
 
-		if currColumnModifier != nil {
-			colModifier.Offset = utils.AddVectors(colModifier.Offset, currColumnModifier.Offset)
-			colModifier.Rotation = utils.AddVectors(colModifier.Rotation, config.Rotation{X: currColumnModifier.Tilt, Y: 0, Z: 0})
+		var columnOffset config.Offset
+		var columnRotation config.Rotation
+		if columnModifier, ok := modifiers.Columns[col]; ok {
+			columnOffset = columnModifier.Offset
+			columnRotation = config.Rotation{X: columnModifier.Tilt, Y: 0, Z: 0}
 		}
+
 		for row := range numRows {
-			var currRowModifier *config.RowColumnModifier
-			if rowModifiers, ok := modifiers.Rows[row]; ok {
-				currRowModifier = &rowModifiers
-			}
-			keyModifier := keyModifier{
-				Offset:   colModifier.Offset,
-				Rotation: colModifier.Rotation,
-				Type:     colModifier.Type,
+			var rowOffset config.Offset
+			var rowRotation config.Rotation
+			if rowModifier, ok := modifiers.Rows[row]; ok {
+				rowOffset = rowModifier.Offset
+				rowRotation = config.Rotation{X: 0, Y: rowModifier.Tilt, Z: 0}
 			}
 
-			if currRowModifier != nil {
-				keyModifier.Offset = utils.AddVectors(keyModifier.Offset, currRowModifier.Offset)
-				keyModifier.Rotation = utils.AddVectors(keyModifier.Rotation, config.Rotation{X: 0, Y: currRowModifier.Tilt, Z: 0})
+			key := keyModifier{Type: config.DefaultSwitchType}
+			override, hasOverride := overrides[matrixIndex{col: col, row: row}]
+			if !hasOverride || !override.IgnoreFingerModifiers {
+				key.Offset = utils.AddVectors(key.Offset, fingerOffset)
+				key.Rotation = utils.AddVectors(key.Rotation, fingerRotation)
+			}
+			if !hasOverride || !override.IgnoreColumnModifiers {
+				key.Offset = utils.AddVectors(key.Offset, columnOffset)
+				key.Rotation = utils.AddVectors(key.Rotation, columnRotation)
+			}
+			if !hasOverride || !override.IgnoreRowModifiers {
+				key.Offset = utils.AddVectors(key.Offset, rowOffset)
+				key.Rotation = utils.AddVectors(key.Rotation, rowRotation)
+			}
+			if hasOverride {
+				key.Offset = utils.AddVectors(key.Offset, override.Offset)
+				key.Rotation = utils.AddVectors(key.Rotation, override.Rotation)
+				if override.SwitchType != "" {
+					key.Type = override.SwitchType
+				}
 			}
-			matrix[col][row] = keyModifier
+			matrix[col][row] = key
 		}
 	}
-	return matrix
+	return matrix, nil
 }
 
-func newTemplateKeywell(keywell config.Keywell, numRows int, numCols int) templateKeywell {
+func newTemplateKeywell(keywell config.Keywell, numRows int, numCols int) (templateKeywell, error) {
+	matrix, err := MatrixModifier(keywell.Modifiers, numRows, numCols, keywell.IndexFingerStartColumn)
+	if err != nil {
+		return templateKeywell{}, errors.Join(errors.New("failed to apply keywell modifiers"), err)
+	}
 	return templateKeywell{
 		TiltAngle:              keywell.TiltAngle,
 		VerticalRadius:         keywell.VerticalRadius,
@@ -100,8 +145,8 @@ func newTemplateKeywell(keywell config.Keywell, numRows int, numCols int) templa
 		InnerLipSize:           keywell.InnerLipSize,
 		OuterLipSize:           keywell.OuterLipSize,
 		indexFingerStartColumn: keywell.IndexFingerStartColumn,
-		Matrix:                 MatrixModifier(keywell.Modifiers, numRows, numCols, keywell.IndexFingerStartColumn),
-	}
+		Matrix:                 matrix,
+	}, nil
 }
 
 type templateThumbCluster struct {
@@ -111,18 +156,12 @@ type templateThumbCluster struct {
 	keys              map[int]config.ThumbKey
 }
 
-func (t *templateThumbCluster) Keys() []*config.ThumbKey {
-	keys := make([]*config.ThumbKey, 0, len(t.keys))
-	for i := range 3 {
-		if key, ok := t.keys[i]; ok {
-			keys = append(keys, &key)
-		} else {
-			keys = append(keys, &config.ThumbKey{
-				Offset:   config.Offset{X: 0, Y: 0, Z: 0},
-				Rotation: config.Rotation{X: 0, Y: 0, Z: 0},
-				Type:     "regular",
-			})
-		}
+// Keys returns the configured thumb keys ordered by their index, the
+// validation makes the indices contiguous from 0.
+func (t *templateThumbCluster) Keys() []config.ThumbKey {
+	keys := make([]config.ThumbKey, 0, len(t.keys))
+	for _, index := range slices.Sorted(maps.Keys(t.keys)) {
+		keys = append(keys, t.keys[index])
 	}
 	return keys
 }
@@ -137,24 +176,63 @@ func newTemplateThumbCluster(thumbCluster config.ThumbCluster) templateThumbClus
 }
 
 type templateData struct {
+	// Half is config.HalfLeft or config.HalfRight.
+	Half         string
 	units        config.Units
 	Layout       config.Layout
 	switches     *switchRepository
 	SwitchTypes  []string
 	Geometry     config.GeometryConfig
+	Controller   config.Controller
+	Bottom       config.Bottom
 	Keywell      templateKeywell
-	Render       config.Render
+	Render       config.RenderProfile
+	Profile      string
 	ThumbCluster templateThumbCluster
+	Model        *geometry.Layout
+}
+
+type templateExtraKey struct {
+	Transform        geometry.Mat4
+	Keycap           geometry.Vec3
+	Type             string
+	Neighbour        geometry.Mat4
+	NeighbourKeycap  geometry.Vec3
+	NeighbourCorners [2]int
+	KeyCorners       [2]int
+}
+
+// ExtraKeys returns the extra keys along with the neighbour corners they are
+// bridged to.
+func (t *templateData) ExtraKeys() []templateExtraKey {
+	keys := make([]templateExtraKey, 0, len(t.Model.Extra))
+	for i, extra := range t.Model.Params.Extra {
+		neighbourCorners, keyCorners := extra.Side.BridgeCorners()
+		keys = append(keys, templateExtraKey{
+			Transform:        t.Model.Extra[i],
+			Keycap:           extra.Keycap,
+			Type:             t.Layout.ExtraKeys[i].SwitchType,
+			Neighbour:        t.Model.Keys[extra.Column][extra.Row],
+			NeighbourKeycap:  t.Model.Params.Keys[extra.Column][extra.Row].Keycap,
+			NeighbourCorners: neighbourCorners,
+			KeyCorners:       keyCorners,
+		})
+	}
+	return keys
 }
 
+// AllSwitchTypes returns the switch type names sorted, so the generated
+// files do not depend on map iteration order.
 func AllSwitchTypes(switches *switchRepository) []string {
-	return slices.Collect(maps.Keys(switches.modules))
+	return slices.Sorted(maps.Keys(switches.modules))
 }
 
 func (t *templateData) AllSwitchIncludes() []string {
 	includes := make([]string, 0, len(t.switches.modules))
-	for name := range t.switches.modules {
-		includes = append(includes, t.switches.modules[name].Filename)
+	for _, name := range AllSwitchTypes(t.switches) {
+		if filename := t.switches.modules[name].Filename; !slices.Contains(includes, filename) {
+			includes = append(includes, filename)
+		}
 	}
 	return includes
 }
@@ -163,28 +241,11 @@ func (t *templateData) AllSwitchModules() *map[string]*config.SwitchModuleDefini
 	return &t.switches.modules
 }
 
-func validateUnits(units config.Units) error {
-	if units.Length != "mm" {
-		return errors.New("only \"mm\" units are supported")
-	}
-	if units.Angle != "deg" {
-		return errors.New("only \"deg\" units are supported")
-	}
-	return nil
-}
-
-func validateLayout(layout config.Layout) error {
-	if layout.Rows <= 1 {
-		return errors.New("rows must be greater than 1")
-	}
-	if layout.Cols <= 4 {
-		return errors.New("cols must be greater than 4")
-	}
-	return nil
-}
-
 func validateSwitchTypes(switchTypes map[string]config.SwitchTypeConfig, repo *switchRepository) (*switchRepository, error) {
-	newRepo := &switchRepository{modules: make(map[string]*config.SwitchModuleDefinition)}
+	newRepo := &switchRepository{
+		modules: make(map[string]*config.SwitchModuleDefinition),
+		pcbs:    make(map[string]*config.PCBDefinition),
+	}
 	for name, switchType := range switchTypes {
 		if switchType.Definition == "" {
 			return nil, errors.New("switch type definition is required for switch type: " + name)
@@ -204,33 +265,59 @@ func validateSwitchTypes(switchTypes map[string]config.SwitchTypeConfig, repo *s
 		if err != nil {
 			return nil, errors.Join(errors.New("failed to add switch module for switch type: "+name), err)
 		}
+		if switchType.PCB != "" {
+			pcb := repo.GetPCB(switchType.PCB)
+			if pcb == nil {
+				return nil, errors.New("pcb definition " + switchType.PCB + " not found for switch type: " + name)
+			}
+			newRepo.pcbs[name] = pcb
+		}
 	}
 	return newRepo, nil
 }
 
-func newTemplateData(config *config.Config, repo *switchRepository) (*templateData, error) {
-	err := validateUnits(config.Units)
+func validateKeywellSwitchTypes(keywell templateKeywell, repo *switchRepository) error {
+	var errs []error
+	for col, keys := range keywell.Matrix {
+		for row, key := range keys {
+			if _, err := repo.GetModule(key.Type); err != nil {
+				errs = append(errs, fmt.Errorf("key at column %d row %d uses undeclared switch type %q", col, row, key.Type))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newTemplateData(config *config.Config, half string, repo *switchRepository, profile string) (*templateData, error) {
+	switchRepo, err := validateSwitchTypes(config.SwitchTypes, repo)
 	if err != nil {
-		return nil, errors.Join(errors.New("failed to validate units"), err)
+		return nil, errors.Join(errors.New("failed to validate switch types"), err)
 	}
-	err = validateLayout(config.Layout)
+	keywell, err := newTemplateKeywell(config.Keywell, config.Layout.Rows, config.Layout.Cols)
 	if err != nil {
-		return nil, errors.Join(errors.New("failed to validate layout"), err)
+		return nil, errors.Join(errors.New("failed to create keywell"), err)
 	}
-	switchRepo, err := validateSwitchTypes(config.SwitchTypes, repo)
+	err = validateKeywellSwitchTypes(keywell, switchRepo)
 	if err != nil {
-		return nil, errors.Join(errors.New("failed to validate switch types"), err)
+		return nil, errors.Join(errors.New("failed to validate keywell switch types"), err)
 	}
 
+	thumbCluster := newTemplateThumbCluster(config.ThumbCluster)
+
 	return &templateData{
+		Half:         half,
 		units:        config.Units,
 		Layout:       config.Layout,
 		switches:     switchRepo,
 		SwitchTypes:  AllSwitchTypes(switchRepo),
 		Geometry:     config.Geometry,
-		Keywell:      newTemplateKeywell(config.Keywell, config.Layout.Rows, config.Layout.Cols),
-		Render:       config.Render,
-		ThumbCluster: newTemplateThumbCluster(config.ThumbCluster),
+		Controller:   config.Controller,
+		Bottom:       config.Bottom,
+		Keywell:      keywell,
+		Render:       config.Render.Profiles[profile],
+		Profile:      profile,
+		ThumbCluster: thumbCluster,
+		Model:        geometry.Compute(newLayoutParams(config, half, switchRepo, keywell, thumbCluster)),
 	}, nil
 }
 
@@ -252,4 +339,112 @@ func scadFormat(value interface{}) string {
 var funcMap = template.FuncMap{
 	"mapKeys":    maps.Keys[map[string]interface{}],
 	"scadFormat": scadFormat,
+	"scadNumber": scadNumber,
+	"scadVector": scadVector,
+	"scadMatrix": scadMatrix,
+}
+
+// OutlineMain returns the case outline transforms up to the thumb cluster.
+func (t *templateData) OutlineMain() []geometry.Mat4 {
+	var transforms []geometry.Mat4
+	for _, point := range t.Model.Outline {
+		if point.Wall != geometry.WallThumb {
+			transforms = append(transforms, point.Transform)
+		}
+	}
+	return transforms
+}
+
+// OutlineThumb returns the case outline transforms along the thumb cluster.
+func (t *templateData) OutlineThumb() []geometry.Mat4 {
+	var transforms []geometry.Mat4
+	for _, point := range t.Model.Outline {
+		if point.Wall == geometry.WallThumb {
+			transforms = append(transforms, point.Transform)
+		}
+	}
+	return transforms
+}
+
+// ControllerSize returns the board size, the preset with size overrides.
+func (t *templateData) ControllerSize() config.ControllerSize {
+	return t.Controller.ResolveSize()
+}
+
+// TrackpointCutouts formats the shrunk keycap cutouts of the trackpoint as
+// ["main", c, r, size], ["extra", index, 0, size] or ["thumb", slot, 0, size],
+// matrix keys use the SCAD indices.
+func (t *templateData) TrackpointCutouts() ([]string, error) {
+	mount := t.Model.Trackpoint
+	if mount == nil {
+		return nil, nil
+	}
+	cutouts := make([]string, 0, len(mount.Cutouts))
+	for _, cutout := range mount.Cutouts {
+		key := cutout.Key
+		var ref string
+		switch {
+		case key.Thumb:
+			ref = fmt.Sprintf(`"thumb", %d, 0`, key.Slot)
+		case key.Extra:
+			ref = fmt.Sprintf(`"extra", %d, 0`, key.Slot)
+		default:
+			ref = fmt.Sprintf(`"main", %d, %d`, key.Row, key.Column)
+		}
+		size, err := scadVector(cutout.Size)
+		if err != nil {
+			return nil, err
+		}
+		cutouts = append(cutouts, ref+", "+size)
+	}
+	return cutouts, nil
+}
+
+type templatePCB struct {
+	SwitchType string
+	Definition *config.PCBDefinition
+}
+
+// PCBs returns the PCB definitions of the switch types that have one, sorted
+// by switch type.
+func (t *templateData) PCBs() []templatePCB {
+	var pcbs []templatePCB
+	for _, name := range t.SwitchTypes {
+		if pcb := t.switches.GetPCB(name); pcb != nil {
+			pcbs = append(pcbs, templatePCB{SwitchType: name, Definition: pcb})
+		}
+	}
+	return pcbs
+}
+
+func (p templatePCB) Outline() string {
+	outline := p.Definition.Outline
+	return scadFormat([]any{outline.Width, outline.Height, outline.CornerRadius})
+}
+
+func (p templatePCB) Offset() string {
+	return scadFormat([]any{p.Definition.Outline.Offset.X, p.Definition.Outline.Offset.Y})
+}
+
+func (p templatePCB) Rim() string {
+	rim := p.Definition.Mount.Rim
+	return scadFormat([]any{rim.Thickness, rim.Clearance})
+}
+
+func (p templatePCB) Screw() string {
+	screw := p.Definition.Mount.Screw
+	positions := make([]any, 0, len(screw.Positions))
+	for _, position := range screw.Positions {
+		positions = append(positions, []any{position.X, position.Y})
+	}
+	return scadFormat([]any{screw.HoleDiameter, screw.BossDiameter, screw.Depth, positions})
+}
+
+func (p templatePCB) Clip() string {
+	clip := p.Definition.Mount.Clip
+	sides := make([]any, 0, len(clip.Sides))
+	for _, side := range clip.Sides {
+		sides = append(sides, side)
+	}
+	return scadFormat([]any{clip.Width, clip.Thickness, clip.Hook, sides})
 }
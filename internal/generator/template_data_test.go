@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"typemon/internal/config"
+)
+
+// layeredModifiers moves key (0, 0) on every layer along its own axis: the
+// finger along X, the column along Y and the row along Z.
+func layeredModifiers(override config.MatrixModifier) config.KeywellModifiers {
+	return config.KeywellModifiers{
+		Finger: config.FingerModifiers{
+			Index: config.FingerModifier{Offset: config.Offset{X: 1}, Tilt: 10},
+		},
+		Columns: map[int]config.RowColumnModifier{0: {Offset: config.Offset{Y: 2}, Tilt: 20}},
+		Rows:    map[int]config.RowColumnModifier{0: {Offset: config.Offset{Z: 4}, Tilt: 30}},
+		Matrix:  []config.MatrixModifier{override},
+	}
+}
+
+func TestMatrixModifierIgnoreFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		override config.MatrixModifier
+		offset   config.Offset
+		rotation config.Rotation
+	}{
+		{"every layer", config.MatrixModifier{}, config.Offset{X: 1, Y: 2, Z: 4}, config.Rotation{X: 30, Y: 30}},
+		{"ignore finger", config.MatrixModifier{IgnoreFingerModifiers: true}, config.Offset{Y: 2, Z: 4}, config.Rotation{X: 20, Y: 30}},
+		{"ignore column", config.MatrixModifier{IgnoreColumnModifiers: true}, config.Offset{X: 1, Z: 4}, config.Rotation{X: 10, Y: 30}},
+		{"ignore row", config.MatrixModifier{IgnoreRowModifiers: true}, config.Offset{X: 1, Y: 2}, config.Rotation{X: 30}},
+		{"own offset on top", config.MatrixModifier{IgnoreRowModifiers: true, Offset: config.Offset{Z: 0.5}}, config.Offset{X: 1, Y: 2, Z: 0.5}, config.Rotation{X: 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix, err := MatrixModifier(layeredModifiers(tt.override), 4, 5, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := matrix[0][0]
+			if key.Offset != tt.offset || key.Rotation != tt.rotation {
+				t.Errorf("key offset %+v rotation %+v, want %+v and %+v", key.Offset, key.Rotation, tt.offset, tt.rotation)
+			}
+			if key.Type != config.DefaultSwitchType {
+				t.Errorf("key type = %q, want the default", key.Type)
+			}
+			// the flags belong to the overridden key only
+			if neighbour := matrix[0][1]; neighbour.Offset != (config.Offset{X: 1, Y: 2}) {
+				t.Errorf("key (0, 1) offset = %+v, want the finger and column offsets", neighbour.Offset)
+			}
+		})
+	}
+}
+
+func TestMatrixModifierOutOfRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		override config.MatrixModifier
+		message  string
+	}{
+		{"column past the layout", config.MatrixModifier{Column: 5}, "column 5 is out of range [0, 5)"},
+		{"negative column", config.MatrixModifier{Column: -1}, "column -1 is out of range [0, 5)"},
+		{"row past the layout", config.MatrixModifier{Row: 4}, "row 4 is out of range [0, 4)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MatrixModifier(layeredModifiers(tt.override), 4, 5, 0)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("err = %v, want %q", err, tt.message)
+			}
+		})
+	}
+}
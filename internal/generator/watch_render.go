@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// WatchRenderer рендерит модель после каждой генерации в режиме watch.
+// Новый рендер отменяет незавершённый, а рендерятся только те части, чьи
+// входные файлы изменились с их последнего успешного рендера.
+type WatchRenderer struct {
+	opts RenderOptions
+	// Rendered is called with the name and output of every job that
+	// succeeded, Finished once a render that was not cancelled ends. Both may
+	// be nil and run on the render goroutine.
+	Rendered func(name string, output string)
+	Finished func(err error)
+
+	// rendered maps the output of every job to the input hash of its last
+	// successful render.
+	mu       sync.Mutex
+	rendered map[string]string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewWatchRenderer(opts RenderOptions) *WatchRenderer {
+	return &WatchRenderer{opts: opts, rendered: make(map[string]string)}
+}
+
+// Stop cancels the render in flight and waits until its OpenSCAD processes
+// are gone.
+func (r *WatchRenderer) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+}
+
+// Start stops the render in flight and renders the outputs of g whose inputs
+// changed in the background. Jobs of a cancelled render stay pending and are
+// picked up by the next one.
+func (r *WatchRenderer) Start(ctx context.Context, g *generator) error {
+	r.Stop()
+	setup, err := g.setupRender(ctx, r.opts)
+	if err != nil {
+		return err
+	}
+	var jobs []renderJob
+	hashes := make(map[string]string)
+	for _, job := range g.renderJobs(setup.key.format) {
+		hash, err := setup.key.hash(job.input)
+		if err != nil {
+			return &RenderError{Job: job.name, Err: err}
+		}
+		r.mu.Lock()
+		changed := r.rendered[job.output] != hash
+		r.mu.Unlock()
+		if changed {
+			jobs = append(jobs, job)
+			hashes[job.output] = hash
+		}
+	}
+	if len(jobs) == 0 {
+		fmt.Println("nothing to render, the model is up to date")
+		if r.Finished != nil {
+			r.Finished(nil)
+		}
+		return nil
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		err := setup.run(ctx, jobs, func(job renderJob) {
+			r.mu.Lock()
+			r.rendered[job.output] = hashes[job.output]
+			r.mu.Unlock()
+			if r.Rendered != nil {
+				r.Rendered(job.name, job.output)
+			}
+		})
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			fmt.Println("failed to render: " + err.Error())
+		}
+		if r.Finished != nil {
+			r.Finished(err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,40 @@
+package geometry
+
+// BottomScrews — винты, которыми дно крепится к бобышкам в стенках.
+type BottomScrews struct {
+	Count int
+	// Clearance is the gap between the plate and the wall feet.
+	Clearance    float64
+	BossDiameter float64
+}
+
+// BottomScrew — бобышка винта дна у стенки.
+type BottomScrew struct {
+	// Wall is the wall foot the boss leans on, Boss the boss center moved
+	// towards the outline center, both on the desk.
+	Wall Vec3
+	Boss Vec3
+}
+
+// computeBottomScrews spreads the screws evenly along the length of the
+// closed outline, the first one at the first wall foot, so dense parts of the
+// outline like the lips do not gather them.
+func (l *Layout) computeBottomScrews() {
+	p := l.Params
+	l.BottomScrews = nil
+	if p.BottomScrews.Count <= 0 || len(l.Outline) < 2 {
+		return
+	}
+	points := make([]Vec3, 0, len(l.Outline)+1)
+	for _, point := range l.Outline {
+		points = append(points, point.Base)
+	}
+	points = append(points, points[0])
+	// the plate inset plus the boss radius, as bottom_plate_inset() in SCAD
+	inset := p.WallBaseThickness/2 + p.BottomScrews.Clearance + p.BottomScrews.BossDiameter/2
+	for i := range p.BottomScrews.Count {
+		wall, _ := placeOnWall(points, float64(i)/float64(p.BottomScrews.Count))
+		boss := wall.Add(l.OutlineCenter.Sub(wall).Normalize().Scale(inset))
+		l.BottomScrews = append(l.BottomScrews, BottomScrew{Wall: wall, Boss: boss})
+	}
+}
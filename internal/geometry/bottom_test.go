@@ -0,0 +1,54 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+// rectangleOutline is a 100x20 outline with most of its points on one short
+// side, like the lips gathering points on a real outline.
+func rectangleOutline(count int) *Layout {
+	l := &Layout{
+		Params: Params{
+			WallBaseThickness: 4,
+			BottomScrews:      BottomScrews{Count: count, Clearance: 1, BossDiameter: 6},
+		},
+		OutlineCenter: Vec3{50, 10, 0},
+	}
+	for _, point := range []Vec3{{0, 0, 0}, {100, 0, 0}, {100, 5, 0}, {100, 10, 0}, {100, 15, 0}, {100, 20, 0}, {0, 20, 0}} {
+		l.Outline = append(l.Outline, OutlinePoint{Wall: WallInner, Base: point})
+	}
+	return l
+}
+
+func TestBottomScrewsFollowOutlineLength(t *testing.T) {
+	l := rectangleOutline(4)
+	l.computeBottomScrews()
+	// the perimeter is 240, a screw every 60 of it
+	want := []Vec3{{0, 0, 0}, {60, 0, 0}, {100, 20, 0}, {40, 20, 0}}
+	if len(l.BottomScrews) != len(want) {
+		t.Fatalf("got %d screws, want %d", len(l.BottomScrews), len(want))
+	}
+	for i, screw := range l.BottomScrews {
+		if !nearVec(screw.Wall, want[i]) {
+			t.Errorf("screw %d at %v, want %v", i, screw.Wall, want[i])
+		}
+	}
+	// the boss moves the plate inset and its radius, 2+1+3, towards the center
+	step := 6 / math.Sqrt2
+	if boss := l.BottomScrews[1].Boss; !nearVec(boss, Vec3{60 - step, step, 0}) {
+		t.Errorf("boss 1 at %v, want %v", boss, Vec3{60 - step, step, 0})
+	}
+}
+
+func TestBottomScrewsDisabled(t *testing.T) {
+	l := rectangleOutline(0)
+	l.computeBottomScrews()
+	if len(l.BottomScrews) != 0 {
+		t.Errorf("got %d screws, want none", len(l.BottomScrews))
+	}
+}
+
+func nearVec(a Vec3, b Vec3) bool {
+	return near(a[0], b[0]) && near(a[1], b[1]) && near(a[2], b[2])
+}
@@ -0,0 +1,154 @@
+package geometry
+
+import (
+	"fmt"
+	"math"
+)
+
+// collisionEpsilon ignores boxes that only touch or overlap by rounding noise.
+const collisionEpsilon = 1e-6
+
+// Box — ориентированный параллелепипед.
+type Box struct {
+	// Transform places the box center and orientation.
+	Transform Mat4
+	// Size is the full box size along its local axes.
+	Size Vec3
+}
+
+// KeyRef identifies a keywell, extra or thumb key in reports.
+type KeyRef struct {
+	Thumb  bool
+	Extra  bool
+	Column int
+	Row    int
+	// Slot is the thumb key index from the config, or the index of an extra
+	// key.
+	Slot int
+}
+
+func (k KeyRef) String() string {
+	if k.Thumb {
+		return fmt.Sprintf("thumb key %d", k.Slot)
+	}
+	if k.Extra {
+		return fmt.Sprintf("extra key %d", k.Slot)
+	}
+	return fmt.Sprintf("column %d row %d", k.Column, k.Row)
+}
+
+// Collision — пересечение колпачков двух клавиш.
+type Collision struct {
+	A KeyRef
+	B KeyRef
+	// Depth is the penetration depth, the smallest distance one keycap has
+	// to move to stop intersecting the other.
+	Depth float64
+}
+
+func (c Collision) String() string {
+	return fmt.Sprintf("keycaps of %s and %s collide, penetration %.2fmm", c.A, c.B, c.Depth)
+}
+
+// keycapBox is the box a keycap sweeps above the switch, from the top of the
+// keywell plane up to the keycap depth.
+func keycapBox(transform Mat4, keycap Vec3) Box {
+	return Box{
+		Transform: transform.Mul(Translate(Vec3{0, 0, keycap[2] / 2})),
+		Size:      keycap,
+	}
+}
+
+// projectionRadius is half the length of the box projected onto axis.
+func (b Box) projectionRadius(axis Vec3) float64 {
+	r := 0.0
+	for i := range 3 {
+		r += b.Size[i] / 2 * math.Abs(b.Transform.Axis(i).Dot(axis))
+	}
+	return r
+}
+
+// Penetration tests two boxes with the separating axis theorem and returns
+// the smallest overlap over all candidate axes, zero when the boxes are
+// separated.
+func Penetration(a Box, b Box) float64 {
+	axes := make([]Vec3, 0, 15)
+	for i := range 3 {
+		axes = append(axes, a.Transform.Axis(i), b.Transform.Axis(i))
+	}
+	for i := range 3 {
+		for j := range 3 {
+			axis := a.Transform.Axis(i).Cross(b.Transform.Axis(j))
+			// parallel edges give no new axis
+			if axis.Len() > 1e-9 {
+				axes = append(axes, axis.Normalize())
+			}
+		}
+	}
+	distance := b.Transform.Translation().Sub(a.Transform.Translation())
+	depth := math.Inf(1)
+	for _, axis := range axes {
+		overlap := a.projectionRadius(axis) + b.projectionRadius(axis) - math.Abs(distance.Dot(axis))
+		if overlap <= 0 {
+			return 0
+		}
+		depth = min(depth, overlap)
+	}
+	return depth
+}
+
+type keycap struct {
+	ref KeyRef
+	box Box
+}
+
+func (l *Layout) keycaps() []keycap {
+	var keycaps []keycap
+	for col := range l.Params.Columns {
+		for row := range l.Params.Rows {
+			size := l.Params.Keys[col][row].Keycap
+			if size == (Vec3{}) || !l.Params.HasKey(col, row) {
+				continue
+			}
+			keycaps = append(keycaps, keycap{
+				ref: KeyRef{Column: col, Row: row},
+				box: keycapBox(l.Keys[col][row], size),
+			})
+		}
+	}
+	for i, extra := range l.Params.Extra {
+		if extra.Keycap == (Vec3{}) {
+			continue
+		}
+		keycaps = append(keycaps, keycap{
+			ref: KeyRef{Extra: true, Slot: i},
+			box: keycapBox(l.Extra[i], extra.Keycap),
+		})
+	}
+	for i, key := range l.Params.Thumb.Keys {
+		if key.Keycap == (Vec3{}) {
+			continue
+		}
+		keycaps = append(keycaps, keycap{
+			ref: KeyRef{Thumb: true, Slot: i},
+			box: keycapBox(l.ThumbKeys[i], key.Keycap),
+		})
+	}
+	return keycaps
+}
+
+// Collisions tests the keycaps of every pair of keys for intersection. Keys
+// without a keycap size are skipped.
+func (l *Layout) Collisions() []Collision {
+	keycaps := l.keycaps()
+	var collisions []Collision
+	for i := range keycaps {
+		for j := i + 1; j < len(keycaps); j++ {
+			depth := Penetration(keycaps[i].box, keycaps[j].box)
+			if depth > collisionEpsilon {
+				collisions = append(collisions, Collision{A: keycaps[i].ref, B: keycaps[j].ref, Depth: depth})
+			}
+		}
+	}
+	return collisions
+}
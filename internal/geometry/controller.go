@@ -0,0 +1,168 @@
+package geometry
+
+import "fmt"
+
+// Controller — плата контроллера у стенки корпуса.
+type Controller struct {
+	// Size is the board width, length and thickness.
+	Size Vec3
+	// ComponentHeight is the space needed above the board, the USB
+	// receptacle included.
+	ComponentHeight float64
+	Wall            Wall
+	// Position is the place along the wall, 0 at its start and 1 at its end
+	// in outline order.
+	Position float64
+	// Floor is the height of the holder bottom above the desk, the top of
+	// the bottom plate, and Elevation the board bottom above it.
+	Floor     float64
+	Elevation float64
+	Clearance float64
+	// HolderWall is the thickness of a holder built around the cavity, zero
+	// when the cavity is only cut out of the case.
+	HolderWall float64
+}
+
+// placeOnWall finds the point at the given fraction of the length of the
+// wall polyline and the wall direction there.
+func placeOnWall(points []Vec3, position float64) (Vec3, Vec3) {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += points[i].Sub(points[i-1]).Len()
+	}
+	left := total * position
+	for i := 1; i < len(points); i++ {
+		segment := points[i].Sub(points[i-1])
+		length := segment.Len()
+		if left <= length || i == len(points)-1 {
+			return points[i-1].Add(segment.Scale(min(left, length) / length)), segment.Normalize()
+		}
+		left -= length
+	}
+	return points[0], Vec3{}
+}
+
+// computeController places the board frame: origin in the middle of the
+// board bottom, Y towards the wall with the USB port, Z up. The board end
+// touches the inner side of the wall foot.
+func (l *Layout) computeController() {
+	p := l.Params
+	c := p.Controller
+	if c == nil {
+		return
+	}
+	points := l.WallPoints(c.Wall)
+	if len(points) < 2 {
+		return
+	}
+	point, along := placeOnWall(points, c.Position)
+	normal := along.Cross(Vec3{0, 0, 1}).Normalize()
+	// the outline goes either way around the center, point the normal out
+	if normal.Dot(point.Sub(l.OutlineCenter)) < 0 {
+		normal = normal.Scale(-1)
+	}
+	x := normal.Cross(Vec3{0, 0, 1})
+	center := point.Sub(normal.Scale(p.WallBaseThickness/2 + c.Clearance + c.Size[1]/2))
+	center[2] = c.Floor + c.Elevation
+	l.Controller = Mat4{
+		{x[0], normal[0], 0, center[0]},
+		{x[1], normal[1], 0, center[1]},
+		{x[2], normal[2], 1, center[2]},
+		{0, 0, 0, 1},
+	}
+	l.HasController = true
+}
+
+// ControllerCavity is the space kept free for the board, its components and
+// the holder around it, from the bottom plate up, in desk coordinates.
+func (l *Layout) ControllerCavity() Box {
+	c := l.Params.Controller
+	height := c.Elevation + c.Size[2] + c.ComponentHeight
+	size := Vec3{
+		c.Size[0] + 2*(c.Clearance+c.HolderWall),
+		c.Size[1] + 2*(c.Clearance+c.HolderWall),
+		height,
+	}
+	return Box{Transform: l.Controller.Mul(Translate(Vec3{0, 0, height/2 - c.Elevation})), Size: size}
+}
+
+// Interference — пересечение детали корпуса или платы с клавишей.
+type Interference struct {
+	// Part names the intersecting case part or PCB.
+	Part string
+	Key  KeyRef
+	// Obstacle is the intersected part of the key, its switch body or the
+	// keywell support around it.
+	Obstacle string
+	Depth    float64
+}
+
+func (i Interference) String() string {
+	return fmt.Sprintf("%s intersects the %s of %s by %.2fmm", i.Part, i.Obstacle, i.Key, i.Depth)
+}
+
+type keyObstacle struct {
+	ref      KeyRef
+	obstacle string
+	box      Box
+}
+
+// keyObstacles returns the switch bodies and the keywell supports under
+// every key in desk coordinates. The support is the key footprint grown by
+// the support radius over the plane thickness.
+func (l *Layout) keyObstacles() []keyObstacle {
+	p := l.Params
+	var obstacles []keyObstacle
+	add := func(ref KeyRef, transform Mat4, keycap Vec3, body Vec3) {
+		transform = l.Base.Mul(transform)
+		support := Vec3{keycap[0] + 2*p.SupportRadius, keycap[1] + 2*p.SupportRadius, p.PlaneThickness}
+		obstacles = append(obstacles, keyObstacle{ref, "keywell support", Box{
+			Transform: transform.Mul(Translate(Vec3{0, 0, -p.PlaneThickness / 2})),
+			Size:      support,
+		}})
+		if body != (Vec3{}) {
+			obstacles = append(obstacles, keyObstacle{ref, "switch", Box{
+				Transform: transform.Mul(Translate(Vec3{0, 0, -body[2] / 2})),
+				Size:      body,
+			}})
+		}
+	}
+	for col := range p.Columns {
+		for row := range p.Rows {
+			if p.HasKey(col, row) {
+				key := p.Keys[col][row]
+				add(KeyRef{Column: col, Row: row}, l.Keys[col][row], key.Keycap, key.Body)
+			}
+		}
+	}
+	for i, extra := range p.Extra {
+		add(KeyRef{Extra: true, Slot: i}, l.Extra[i], extra.Keycap, extra.Body)
+	}
+	for i, key := range p.Thumb.Keys {
+		add(KeyRef{Thumb: true, Slot: i}, l.ThumbKeys[i], key.Keycap, key.Body)
+	}
+	return obstacles
+}
+
+// interferences tests a case part against every switch and keywell support.
+func (l *Layout) interferences(part string, box Box) []Interference {
+	var found []Interference
+	for _, obstacle := range l.keyObstacles() {
+		depth := Penetration(box, obstacle.box)
+		if depth > collisionEpsilon {
+			found = append(found, Interference{Part: part, Key: obstacle.ref, Obstacle: obstacle.obstacle, Depth: depth})
+		}
+	}
+	return found
+}
+
+// Interferences reports case parts that cut into switches or keywell
+// supports, and PCBs that collide with other keys.
+func (l *Layout) Interferences() []Interference {
+	var found []Interference
+	if l.HasController {
+		found = append(found, l.interferences("controller cavity", l.ControllerCavity())...)
+	}
+	found = append(found, l.trackpointInterferences()...)
+	return append(found, l.pcbInterferences()...)
+}
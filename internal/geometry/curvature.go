@@ -0,0 +1,93 @@
+package geometry
+
+import "math"
+
+// Curve — профиль сечения keywell вдоль одной оси.
+type Curve interface {
+	// Elevation returns the height of the curve at distance d from its
+	// lowest point and the slope angle there in degrees, both for d >= 0.
+	Elevation(d float64) (float64, float64)
+}
+
+// Flat keeps the axis straight.
+type Flat struct{}
+
+func (Flat) Elevation(d float64) (float64, float64) {
+	return 0, 0
+}
+
+// Circle is a circular arc, the profile used by the spherical keywell.
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Elevation(d float64) (float64, float64) {
+	elevation := c.Radius * (1 - math.Sqrt(1-d*d/c.Radius/c.Radius))
+	return elevation, acos(1 - elevation/c.Radius)
+}
+
+// Parabola has the same curvature as a circle of Radius at its lowest point
+// and rises slower away from it.
+type Parabola struct {
+	Radius float64
+}
+
+func (p Parabola) Elevation(d float64) (float64, float64) {
+	return d * d / (2 * p.Radius), atan2(d, p.Radius)
+}
+
+// Ellipse is an elliptical arc with semi-axis Radius along the keywell plane
+// and Depth along Z, a circle when both are equal.
+type Ellipse struct {
+	Radius float64
+	Depth  float64
+}
+
+func (e Ellipse) Elevation(d float64) (float64, float64) {
+	root := math.Sqrt(1 - d*d/e.Radius/e.Radius)
+	slope := e.Depth * d / (e.Radius * e.Radius * root)
+	return e.Depth * (1 - root), atan2(slope, 1)
+}
+
+// ExplicitColumn — заданные вручную высоты и наклоны клавиш колонки.
+type ExplicitColumn struct {
+	// Heights and Tilts hold a value per row, missing rows are 0.
+	Heights []float64
+	Tilts   []float64
+	// SideTilt rotates the whole column around the X axis.
+	SideTilt float64
+}
+
+// Curvature — модель поверхности keywell. Vertical curves the keys along a
+// column (X axis), Horizontal across the columns (Y axis). When Explicit is
+// set it replaces both curves.
+type Curvature struct {
+	Vertical   Curve
+	Horizontal Curve
+	// Explicit holds a column per keywell column.
+	Explicit []ExplicitColumn
+}
+
+func valueAt(values []float64, i int) float64 {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
+
+// surface returns the height and rotation of the keywell surface for a key
+// at distance circ from the curvature center.
+func (c Curvature) surface(col int, row int, circ Vec3) (float64, Vec3) {
+	if c.Explicit != nil {
+		column := c.Explicit[col]
+		return valueAt(column.Heights, row), Vec3{column.SideTilt, valueAt(column.Tilts, row), 0}
+	}
+	vertical, verticalAngle := c.Vertical.Elevation(math.Abs(circ[0]))
+	horizontal, horizontalAngle := c.Horizontal.Elevation(math.Abs(circ[1]))
+	rotation := Vec3{
+		horizontalAngle * sign(circ[1]),
+		verticalAngle * -sign(circ[0]),
+		0,
+	}
+	return vertical + horizontal, rotation
+}
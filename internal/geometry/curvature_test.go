@@ -0,0 +1,101 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+const testTolerance = 1e-4
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < testTolerance
+}
+
+func TestCurveElevation(t *testing.T) {
+	tests := []struct {
+		name      string
+		curve     Curve
+		d         float64
+		elevation float64
+		angle     float64
+	}{
+		{"flat at center", Flat{}, 0, 0, 0},
+		{"flat mid", Flat{}, 50, 0, 0},
+		{"flat far", Flat{}, 1000, 0, 0},
+
+		{"circle at center", Circle{Radius: 100}, 0, 0, 0},
+		// 60-80-100 triangle
+		{"circle mid", Circle{Radius: 100}, 60, 20, 36.8699},
+		{"circle edge", Circle{Radius: 100}, 100, 100, 90},
+
+		{"parabola at center", Parabola{Radius: 100}, 0, 0, 0},
+		{"parabola mid", Parabola{Radius: 100}, 50, 12.5, 26.5651},
+		{"parabola at radius", Parabola{Radius: 100}, 100, 50, 45},
+
+		{"ellipse at center", Ellipse{Radius: 100, Depth: 50}, 0, 0, 0},
+		{"ellipse mid", Ellipse{Radius: 100, Depth: 50}, 60, 10, 20.5560},
+		{"ellipse edge", Ellipse{Radius: 100, Depth: 50}, 100, 50, 90},
+		{"ellipse as circle", Ellipse{Radius: 100, Depth: 100}, 60, 20, 36.8699},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elevation, angle := tt.curve.Elevation(tt.d)
+			if !near(elevation, tt.elevation) || !near(angle, tt.angle) {
+				t.Errorf("Elevation(%v) = %v, %v, want %v, %v", tt.d, elevation, angle, tt.elevation, tt.angle)
+			}
+		})
+	}
+}
+
+// Arcs end at their radius, config validation keeps the keywell inside it.
+func TestCurveElevationOutOfRange(t *testing.T) {
+	for _, curve := range []Curve{Circle{Radius: 30}, Ellipse{Radius: 30, Depth: 20}} {
+		elevation, angle := curve.Elevation(39)
+		if !math.IsNaN(elevation) || !math.IsNaN(angle) {
+			t.Errorf("%#v: Elevation(39) = %v, %v, want NaN beyond the radius", curve, elevation, angle)
+		}
+	}
+	elevation, angle := Parabola{Radius: 30}.Elevation(39)
+	if math.IsNaN(elevation) || math.IsNaN(angle) {
+		t.Errorf("parabola: Elevation(39) = %v, %v, want a value at any distance", elevation, angle)
+	}
+}
+
+func TestCurvatureSurface(t *testing.T) {
+	spherical := Curvature{Vertical: Circle{Radius: 100}, Horizontal: Circle{Radius: 100}}
+	explicit := Curvature{Explicit: []ExplicitColumn{
+		{Heights: []float64{1, 2}, Tilts: []float64{5, -5}, SideTilt: 3},
+	}}
+	tests := []struct {
+		name      string
+		curvature Curvature
+		row       int
+		circ      Vec3
+		height    float64
+		rotation  Vec3
+	}{
+		{"spherical at center", spherical, 0, Vec3{}, 0, Vec3{}},
+		// keys tilt towards the center on both axes
+		{"spherical front", spherical, 0, Vec3{60, 0, 0}, 20, Vec3{0, -36.8699, 0}},
+		{"spherical back", spherical, 0, Vec3{-60, 0, 0}, 20, Vec3{0, 36.8699, 0}},
+		{"spherical side", spherical, 0, Vec3{0, -60, 0}, 20, Vec3{-36.8699, 0, 0}},
+		{"spherical corner", spherical, 0, Vec3{60, 60, 0}, 40, Vec3{36.8699, -36.8699, 0}},
+		{"explicit first row", explicit, 0, Vec3{60, 60, 0}, 1, Vec3{3, 5, 0}},
+		{"explicit last row", explicit, 1, Vec3{}, 2, Vec3{3, -5, 0}},
+		{"explicit missing row", explicit, 2, Vec3{}, 0, Vec3{3, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			height, rotation := tt.curvature.surface(0, tt.row, tt.circ)
+			if !near(height, tt.height) {
+				t.Errorf("height = %v, want %v", height, tt.height)
+			}
+			for i := range rotation {
+				if !near(rotation[i], tt.rotation[i]) {
+					t.Errorf("rotation = %v, want %v", rotation, tt.rotation)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package geometry
+
+import "math"
+
+// KeyModifier — итоговое смещение и поворот клавиши после всех модификаторов.
+type KeyModifier struct {
+	SwitchType string
+	Offset     Vec3
+	Rotation   Vec3
+	// Keycap is the keycap width, height and depth. Width and height are
+	// also the key footprint on the plane.
+	Keycap Vec3
+	// Body is the switch body below the top of the keywell plane, zero when
+	// unknown.
+	Body Vec3
+	// PCB is nil for keys without a PCB.
+	PCB *PCB
+}
+
+// ThumbKey — клавиша кластера большого пальца.
+type ThumbKey struct {
+	SwitchType string
+	Offset     Vec3
+	Rotation   Vec3
+	Keycap     Vec3
+	Body       Vec3
+	PCB        *PCB
+}
+
+// Thumb — параметры кластера большого пальца.
+type Thumb struct {
+	// OriginColumn — колонка, от последней клавиши которой строится кластер.
+	OriginColumn int
+	Offset       Vec3
+	Rotation     Vec3
+	Keys         []ThumbKey
+}
+
+// Splay — поворот колонок вокруг оси Z.
+type Splay struct {
+	// Angles holds the splay angle of every column.
+	Angles []float64
+	// HomeRow pivots every column around the front edge of its key in this
+	// row. When negative all columns pivot around Point instead.
+	HomeRow int
+	Point   Vec3
+}
+
+// Params — всё, что нужно для расчёта положения клавиш одной половины.
+//
+// Columns are finger columns laid out along Y, rows are keys of a column laid
+// out along X. In the generated SCAD columns are num_rows and rows are
+// num_cols, see config.scad.tmpl.
+type Params struct {
+	Columns int
+	Rows    int
+	// RowPitch is the distance between neighbouring rows along X
+	// (col_spacing_x in SCAD).
+	RowPitch float64
+	// ColumnPitch is the distance between neighbouring columns along Y
+	// (row_spacing_y in SCAD).
+	ColumnPitch float64
+	// ThumbPitch is the distance between neighbouring thumb keys.
+	ThumbPitch float64
+
+	PlaneThickness float64
+	SupportRadius  float64
+	Elevation      float64
+	TiltAngle      float64
+	Tenting        Tenting
+
+	InnerLipSize      float64
+	OuterLipSize      float64
+	WallBaseThickness float64
+	// WallCenterOffset pushes the wall feet away from the outline center,
+	// a fraction of their distance to it.
+	WallCenterOffset float64
+
+	Curvature    Curvature
+	CenterOffset Vec3
+
+	// Keys holds per key modifiers indexed by [column][row].
+	Keys [][]KeyModifier
+	// Present marks the keys that exist, indexed by [column][row].
+	Present [][]bool
+	Extra   []ExtraKey
+	Splay   Splay
+	Thumb   Thumb
+	// Controller is nil when the half has no controller.
+	Controller *Controller
+	// Trackpoint is nil when the half has no trackpoint.
+	Trackpoint *Trackpoint
+
+	BottomScrews BottomScrews
+}
+
+// Layout — рассчитанные преобразования клавиш, те же, что раньше считались
+// в SCAD функциями M_key_main, M_thumb_key и M_base.
+type Layout struct {
+	Params Params
+	// Keys are keywell key transforms on the keywell plane, [column][row],
+	// missing keys still get a transform.
+	Keys  [][]Mat4
+	Extra []Mat4
+	// InnerEdge and OuterEdge hold one key per row, BackEdge and FrontEdge
+	// one key per column.
+	InnerEdge []EdgeKey
+	OuterEdge []EdgeKey
+	BackEdge  []EdgeKey
+	FrontEdge []EdgeKey
+	// FrontWall holds the front keys outside of the thumb cluster that get a
+	// wall, only when the tent lifts the front edge.
+	FrontWall []EdgeKey
+
+	ThumbOrigin EdgeKey
+	ThumbPlane  Mat4
+	ThumbKeys   []Mat4
+	// BaseTilt is the keywell tilt followed by the tent.
+	BaseTilt Mat4
+	Base     Mat4
+
+	// InnerLip and OuterLip hold the lip ends, two per edge key.
+	InnerLip []Mat4
+	OuterLip []Mat4
+	// Outline is the case outline in desk coordinates.
+	Outline       []OutlinePoint
+	OutlineCenter Vec3
+
+	// Controller is the board frame, see computeController.
+	Controller    Mat4
+	HasController bool
+
+	// Trackpoint is nil when the half has no trackpoint.
+	Trackpoint *TrackpointMount
+
+	// BottomScrews are the bottom plate screws along the outline.
+	BottomScrews []BottomScrew
+}
+
+func (p Params) basePosition(col int, row int) Vec3 {
+	return Vec3{
+		(float64(row) - float64(p.Rows-1)/2) * p.RowPitch,
+		-(float64(col) - float64(p.Columns-1)/2) * p.ColumnPitch,
+		0,
+	}
+}
+
+func (p Params) keyTransform(col int, row int) Mat4 {
+	base := p.basePosition(col, row)
+	// the grid is centered around the origin, so its center is the offset
+	circ := base.Sub(p.CenterOffset)
+	elevation, rotation := p.Curvature.surface(col, row, circ)
+
+	modifier := p.Keys[col][row]
+	position := Vec3{base[0], base[1], elevation}.Add(modifier.Offset)
+	return Translate(position).Mul(Rotate(rotation.Add(modifier.Rotation)))
+}
+
+func (p Params) columnSplay(col int) float64 {
+	if col < 0 || col >= len(p.Splay.Angles) {
+		return 0
+	}
+	return p.Splay.Angles[col]
+}
+
+// splayTransform rotates a whole column around the Z axis about its pivot,
+// the pivot is on the keywell plane before curvature.
+func (p Params) splayTransform(col int) Mat4 {
+	if p.columnSplay(col) == 0 {
+		return Identity()
+	}
+	pivot := p.Splay.Point
+	if p.Splay.HomeRow >= 0 {
+		pivot = p.basePosition(col, p.Splay.HomeRow).Add(Vec3{p.RowPitch / 2, 0, 0})
+	}
+	return Translate(pivot).Mul(Mz(p.Splay.Angles[col])).Mul(Translate(pivot.Scale(-1)))
+}
+
+// KeyCorner returns the local transform of a corner of a key footprint on the
+// bottom of the keywell plane: 0 top-left, 1 top-right, 2 bottom-left,
+// 3 bottom-right.
+func (p Params) KeyCorner(size Vec3, corner int) Mat4 {
+	x := size[0] / 2
+	y := size[1] / 2
+	if corner%2 == 0 {
+		x = -x
+	}
+	if corner >= 2 {
+		y = -y
+	}
+	return Translate(Vec3{x, y, -p.PlaneThickness})
+}
+
+// minKeyHeight is the lowest key corner of the keys from lowKeys after the
+// tilt and the tent, pushed further by the support radius.
+func (l *Layout) minKeyHeight() float64 {
+	lowest := math.Inf(1)
+	for _, key := range l.lowKeys() {
+		for corner := range 4 {
+			point := l.BaseTilt.Mul(key.transform).Mul(l.Params.KeyCorner(key.size, corner)).Translation()
+			lowest = min(lowest, point[2])
+		}
+	}
+	return lowest + l.Params.SupportRadius*sign(lowest)
+}
+
+// Compute calculates every key transform of one half.
+func Compute(p Params) *Layout {
+	l := &Layout{Params: p, Keys: make([][]Mat4, p.Columns)}
+	for col := range p.Columns {
+		l.Keys[col] = make([]Mat4, p.Rows)
+		splay := p.splayTransform(col)
+		for row := range p.Rows {
+			l.Keys[col][row] = splay.Mul(p.keyTransform(col, row))
+		}
+	}
+
+	l.Extra = make([]Mat4, len(p.Extra))
+	for i, extra := range p.Extra {
+		l.Extra[i] = l.Keys[extra.Column][extra.Row].
+			Mul(Translate(p.sideStep(extra.Side))).
+			Mul(Translate(extra.Offset)).
+			Mul(Rotate(extra.Rotation))
+	}
+	l.computeEdges()
+
+	// the cluster is attached to the front key of the origin column
+	thumb := p.Thumb
+	l.ThumbOrigin = l.FrontEdge[thumb.OriginColumn]
+	l.ThumbPlane = l.ThumbOrigin.Transform.
+		Mul(Translate(thumb.Offset.Add(Vec3{p.RowPitch, 0, 0}))).
+		Mul(Rotate(thumb.Rotation))
+	l.ThumbKeys = make([]Mat4, len(thumb.Keys))
+	for i, key := range thumb.Keys {
+		l.ThumbKeys[i] = l.ThumbPlane.
+			Mul(Translate(key.Offset)).
+			Mul(Translate(Vec3{0, p.ThumbPitch * float64(i), 0})).
+			Mul(Rotate(key.Rotation))
+	}
+
+	tilt := Rotate(Vec3{p.TiltAngle, 0, 0})
+	l.BaseTilt = l.tentTransform(tilt).Mul(tilt)
+	l.computeFrontWall()
+	lowest := l.minKeyHeight()
+	l.Base = Translate(Vec3{0, 0, math.Abs(lowest + p.Elevation*sign(lowest))}).Mul(l.BaseTilt)
+	l.computeOutline()
+	l.computeBottomScrews()
+	l.computeController()
+	l.computeTrackpoint()
+	return l
+}
@@ -0,0 +1,81 @@
+package geometry_test
+
+import (
+	"math"
+	"testing"
+	"typemon/internal/config"
+	"typemon/internal/generator"
+	"typemon/internal/geometry"
+)
+
+// The reference matrices are M_key_main, M_thumb_key and M_base of the
+// baseline SCAD functions evaluated for configs/default.yml, only the first
+// three rows are compared. SCAD M_key_main(c, r) is Keys[r][c].
+func TestComputeMatchesBaselineSCAD(t *testing.T) {
+	t.Chdir("../..")
+	gen, _, err := generator.New("default", generator.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout, err := gen.Layout(config.HalfLeft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  geometry.Mat4
+		want [3][4]float64
+	}{
+		{"M_key_main(0, 0)", layout.Keys[0][0], [3][4]float64{
+			{0.9607, 0, 0.2775, -27.75},
+			{0.0902, 0.9457, -0.3122, 39},
+			{-0.2624, 0.325, 0.9086, 10.4418},
+		}},
+		{"M_key_main(3, 2)", layout.Keys[2][3], [3][4]float64{
+			{0.9607, 0, -0.2775, 27.75},
+			{0, 1, 0, 0},
+			{0.2775, 0, 0.9607, 3.9274},
+		}},
+		{"M_key_main(1, 4)", layout.Keys[4][1], [3][4]float64{
+			{0.9957, 0, 0.0925, -9.25},
+			{-0.0301, 0.9457, 0.3236, -39},
+			{-0.0875, -0.325, 0.9417, 6.943},
+		}},
+		{"M_key_main(2, 1)", layout.Keys[1][2], [3][4]float64{
+			{0.9957, 0, -0.0925, 9.25},
+			{-0.015, 0.9867, -0.1618, 19.5},
+			{0.0913, 0.1625, 0.9825, 2.0237},
+		}},
+		{"M_thumb_key(0)", layout.ThumbKeys[0], [3][4]float64{
+			{0.9461, 0.1569, 0.2832, 50.3271},
+			{-0.2157, -0.3467, 0.9128, 28.3074},
+			{0.2414, -0.9247, -0.2942, 13.582},
+		}},
+		{"M_thumb_key(1)", layout.ThumbKeys[1], [3][4]float64{
+			{0.9289, 0.186, 0.3204, 53.7664},
+			{-0.1847, -0.5172, 0.8357, 19.5045},
+			{0.3211, -0.8354, -0.4461, -3.6207},
+		}},
+		{"M_thumb_key(2)", layout.ThumbKeys[2], [3][4]float64{
+			{0.92, 0.1885, 0.3437, 57.5855},
+			{-0.1719, -0.5941, 0.7858, 8.6603},
+			{0.3523, -0.782, -0.5141, -19.9934},
+		}},
+		{"M_base", layout.Base, [3][4]float64{
+			{1, 0, 0, 0},
+			{0, 0.766, -0.6428, 0},
+			{0, 0.6428, 0.766, 30.4746},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, row := range tt.want {
+				for j, want := range row {
+					if math.Abs(tt.got[i][j]-want) > 1e-4 {
+						t.Fatalf("[%d][%d] = %v, want %v, matrix %v", i, j, tt.got[i][j], want, tt.got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package geometry
+
+import "math"
+
+// Vec3 — точка или вектор в пространстве модели.
+type Vec3 [3]float64
+
+// Mat4 — матрица преобразования 4x4 в той же раскладке, что и в
+// scad/lib/linear_algebra.scad: строки, перенос в последнем столбце.
+type Mat4 [4][4]float64
+
+func (v Vec3) Add(u Vec3) Vec3 {
+	return Vec3{v[0] + u[0], v[1] + u[1], v[2] + u[2]}
+}
+
+func (v Vec3) Sub(u Vec3) Vec3 {
+	return Vec3{v[0] - u[0], v[1] - u[1], v[2] - u[2]}
+}
+
+func (v Vec3) Scale(s float64) Vec3 {
+	return Vec3{v[0] * s, v[1] * s, v[2] * s}
+}
+
+func (v Vec3) Dot(u Vec3) float64 {
+	return v[0]*u[0] + v[1]*u[1] + v[2]*u[2]
+}
+
+func (v Vec3) Cross(u Vec3) Vec3 {
+	return Vec3{
+		v[1]*u[2] - v[2]*u[1],
+		v[2]*u[0] - v[0]*u[2],
+		v[0]*u[1] - v[1]*u[0],
+	}
+}
+
+func (v Vec3) Len() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+func (v Vec3) Normalize() Vec3 {
+	l := v.Len()
+	if l == 0 {
+		return v
+	}
+	return v.Scale(1 / l)
+}
+
+func sin(angle float64) float64 {
+	return math.Sin(angle * math.Pi / 180)
+}
+
+func cos(angle float64) float64 {
+	return math.Cos(angle * math.Pi / 180)
+}
+
+func acos(x float64) float64 {
+	return math.Acos(x) * 180 / math.Pi
+}
+
+func atan2(y float64, x float64) float64 {
+	return math.Atan2(y, x) * 180 / math.Pi
+}
+
+func sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func Identity() Mat4 {
+	return Mat4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Mx, My and Mz rotate around the corresponding axis, angles are in degrees.
+func Mx(angle float64) Mat4 {
+	return Mat4{
+		{1, 0, 0, 0},
+		{0, cos(angle), -sin(angle), 0},
+		{0, sin(angle), cos(angle), 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func My(angle float64) Mat4 {
+	return Mat4{
+		{cos(angle), 0, sin(angle), 0},
+		{0, 1, 0, 0},
+		{-sin(angle), 0, cos(angle), 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func Mz(angle float64) Mat4 {
+	return Mat4{
+		{cos(angle), -sin(angle), 0, 0},
+		{sin(angle), cos(angle), 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Rotate matches Mrotate from linear_algebra.scad: Z * X * Y.
+func Rotate(r Vec3) Mat4 {
+	return Mz(r[2]).Mul(Mx(r[0])).Mul(My(r[1]))
+}
+
+func Translate(p Vec3) Mat4 {
+	return Mat4{
+		{1, 0, 0, p[0]},
+		{0, 1, 0, p[1]},
+		{0, 0, 1, p[2]},
+		{0, 0, 0, 1},
+	}
+}
+
+func Scale(s Vec3) Mat4 {
+	return Mat4{
+		{s[0], 0, 0, 0},
+		{0, s[1], 0, 0},
+		{0, 0, s[2], 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func (m Mat4) Mul(n Mat4) Mat4 {
+	var out Mat4
+	for i := range 4 {
+		for j := range 4 {
+			for k := range 4 {
+				out[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// Apply transforms a point, like transform_point in linear_algebra.scad.
+func (m Mat4) Apply(p Vec3) Vec3 {
+	var out Vec3
+	for i := range 3 {
+		out[i] = m[i][0]*p[0] + m[i][1]*p[1] + m[i][2]*p[2] + m[i][3]
+	}
+	return out
+}
+
+// ApplyVector transforms a direction, ignoring the translation.
+func (m Mat4) ApplyVector(v Vec3) Vec3 {
+	var out Vec3
+	for i := range 3 {
+		out[i] = m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+	}
+	return out
+}
+
+func (m Mat4) Translation() Vec3 {
+	return Vec3{m[0][3], m[1][3], m[2][3]}
+}
+
+// Axis returns the i-th column of the rotation part, the direction of the
+// local X, Y or Z axis in world space.
+func (m Mat4) Axis(i int) Vec3 {
+	return Vec3{m[0][i], m[1][i], m[2][i]}
+}
+
+// Euler returns rotation angles r such that Rotate(r) equals the rotation
+// part of m, assuming m has no scale.
+func (m Mat4) Euler() Vec3 {
+	// Rotate(r) = Mz * Mx * My, so m[2][1] = sin(x)
+	x := math.Asin(math.Max(-1, math.Min(1, m[2][1]))) * 180 / math.Pi
+	var r Vec3
+	if math.Abs(m[2][1]) < 1-1e-9 {
+		r = Vec3{x, atan2(-m[2][0], m[2][2]), atan2(-m[0][1], m[1][1])}
+	} else {
+		// gimbal lock, the Y rotation is folded into Z
+		r = Vec3{x, 0, atan2(m[1][0], m[0][0])}
+	}
+	for i := range r {
+		// avoid -0 in reports
+		if r[i] == 0 {
+			r[i] = 0
+		}
+	}
+	return r
+}
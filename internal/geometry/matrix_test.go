@@ -0,0 +1,102 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func nearMatrix(a Mat4, b Mat4) bool {
+	for i := range a {
+		for j := range a[i] {
+			if !near(a[i][j], b[i][j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestAxisRotations(t *testing.T) {
+	s := math.Sqrt(3) / 2
+	tests := []struct {
+		name string
+		got  Mat4
+		want Mat4
+	}{
+		{"Mx(90)", Mx(90), Mat4{{1, 0, 0, 0}, {0, 0, -1, 0}, {0, 1, 0, 0}, {0, 0, 0, 1}}},
+		{"My(90)", My(90), Mat4{{0, 0, 1, 0}, {0, 1, 0, 0}, {-1, 0, 0, 0}, {0, 0, 0, 1}}},
+		{"Mz(90)", Mz(90), Mat4{{0, -1, 0, 0}, {1, 0, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}},
+		{"Mx(-60)", Mx(-60), Mat4{{1, 0, 0, 0}, {0, 0.5, s, 0}, {0, -s, 0.5, 0}, {0, 0, 0, 1}}},
+		{"My(30)", My(30), Mat4{{s, 0, 0.5, 0}, {0, 1, 0, 0}, {-0.5, 0, s, 0}, {0, 0, 0, 1}}},
+		{"Mz(0)", Mz(0), Identity()},
+		// Mrotate([40, 0, 0]), the default M_base_tilt
+		{"Rotate x", Rotate(Vec3{40, 0, 0}), Mat4{{1, 0, 0, 0}, {0, 0.766, -0.6428, 0}, {0, 0.6428, 0.766, 0}, {0, 0, 0, 1}}},
+		// Mrotate([-120, 0, -10]) = Mz(-10) * Mx(-120) * My(0), the default
+		// thumb plane rotation
+		{"Rotate order", Rotate(Vec3{-120, 0, -10}), Mat4{
+			{0.9848, -0.0868, 0.1504, 0},
+			{-0.1736, -0.4924, 0.8529, 0},
+			{0, -0.866, -0.5, 0},
+			{0, 0, 0, 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !nearMatrix(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateAndApply(t *testing.T) {
+	m := Translate(Vec3{1, 2, 3}).Mul(Mz(90))
+	if got := m.Apply(Vec3{1, 0, 0}); !near(got[0], 1) || !near(got[1], 3) || !near(got[2], 3) {
+		t.Errorf("Apply = %v, want [1 3 3]", got)
+	}
+	if got := m.ApplyVector(Vec3{1, 0, 0}); !near(got[0], 0) || !near(got[1], 1) || !near(got[2], 0) {
+		t.Errorf("ApplyVector = %v, want [0 1 0]", got)
+	}
+	if got := m.Translation(); got != (Vec3{1, 2, 3}) {
+		t.Errorf("Translation = %v, want [1 2 3]", got)
+	}
+}
+
+func TestEulerRoundTrip(t *testing.T) {
+	rotations := []Vec3{
+		{0, 0, 0},
+		{40, 0, 0},
+		{0, -16.1111, 0},
+		{-18.9656, 16.1111, 0},
+		{-120, 0, -10},
+		{-15, 0, -7},
+		{30, 45, 60},
+		{-89, 170, -179},
+	}
+	for _, r := range rotations {
+		m := Rotate(r)
+		got := m.Euler()
+		// angles may come back as an equivalent triple, the matrix must not
+		// change
+		if !nearMatrix(Rotate(got), m) {
+			t.Errorf("Euler(Rotate(%v)) = %v, which is a different rotation", r, got)
+		}
+		// within (-90, 90) around X the angles themselves round trip
+		if math.Abs(r[0]) < 90 {
+			for i := range r {
+				if !near(got[i], r[i]) {
+					t.Errorf("Euler(Rotate(%v)) = %v", r, got)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestEulerGimbalLock(t *testing.T) {
+	m := Rotate(Vec3{90, 30, 20})
+	got := m.Euler()
+	if !near(got[0], 90) || !nearMatrix(Rotate(got), m) {
+		t.Errorf("Euler(Rotate([90 30 20])) = %v, which is a different rotation", got)
+	}
+}
@@ -0,0 +1,102 @@
+package geometry
+
+// Wall — участок стенки корпуса вдоль контура.
+type Wall string
+
+const (
+	WallInner Wall = "inner"
+	WallBack  Wall = "back"
+	WallOuter Wall = "outer"
+	WallFront Wall = "front"
+	WallThumb Wall = "thumb"
+)
+
+// OutlinePoint — точка контура корпуса, от которой стенка идёт вниз к столу.
+type OutlinePoint struct {
+	Wall Wall
+	// Transform places the support shape on top of the wall, in desk
+	// coordinates, M_base included.
+	Transform Mat4
+	// Base is the wall foot on the desk, the Transform origin projected on
+	// the XY plane and pushed away from the outline center.
+	Base Vec3
+}
+
+// lipParts returns the transforms of the inner and outer lip ends, two per
+// edge key, the same as M_keywell_plane_inner_lip_part and
+// M_keywell_plane_outer_lip_part did in SCAD.
+func (l *Layout) lipParts() (inner []Mat4, outer []Mat4) {
+	p := l.Params
+	// the lips keep out of the keywell tilt
+	untilt := Mx(-p.TiltAngle)
+	for _, key := range l.InnerEdge {
+		for corner := range 2 {
+			corner := key.Transform.Mul(p.KeyCorner(key.Keycap, corner))
+			inner = append(inner, corner.Mul(untilt).Mul(Translate(Vec3{0, p.InnerLipSize, 0})))
+		}
+	}
+	for _, key := range l.OuterEdge {
+		for corner := 2; corner < 4; corner++ {
+			corner := key.Transform.Mul(p.KeyCorner(key.Keycap, corner))
+			// the outer lip follows the column splay
+			outer = append(outer, Translate(corner.Translation()).Mul(Mz(key.Splay)).Mul(untilt).Mul(Translate(Vec3{0, -p.OuterLipSize, 0})))
+		}
+	}
+	return inner, outer
+}
+
+// computeOutline walks the case outline: inner lip from the front to the
+// back, back wall, outer lip back to the front, the front wall of a tented
+// half and the last thumb key.
+func (l *Layout) computeOutline() {
+	p := l.Params
+	l.InnerLip, l.OuterLip = l.lipParts()
+	l.Outline = nil
+	add := func(wall Wall, transform Mat4) {
+		l.Outline = append(l.Outline, OutlinePoint{Wall: wall, Transform: l.Base.Mul(transform)})
+	}
+	for i := len(l.InnerLip) - 1; i >= 0; i-- {
+		add(WallInner, l.InnerLip[i])
+	}
+	for _, key := range l.BackEdge {
+		for corner := 0; corner < 4; corner += 2 {
+			add(WallBack, key.Transform.Mul(p.KeyCorner(key.Keycap, corner)))
+		}
+	}
+	for _, part := range l.OuterLip {
+		add(WallOuter, part)
+	}
+	for _, key := range l.FrontWall {
+		for corner := 3; corner > 0; corner -= 2 {
+			add(WallFront, key.Transform.Mul(p.KeyCorner(key.Keycap, corner)))
+		}
+	}
+	if n := len(p.Thumb.Keys); n > 0 {
+		for corner := range 2 {
+			add(WallThumb, l.ThumbKeys[n-1].Mul(p.KeyCorner(p.Thumb.Keys[n-1].Keycap, corner)))
+		}
+	}
+
+	var center Vec3
+	for i := range l.Outline {
+		point := l.Outline[i].Transform.Translation()
+		l.Outline[i].Base = Vec3{point[0], point[1], 0}
+		center = center.Add(l.Outline[i].Base)
+	}
+	center = center.Scale(1 / float64(len(l.Outline)))
+	for i := range l.Outline {
+		l.Outline[i].Base = center.Add(l.Outline[i].Base.Sub(center).Scale(1 + p.WallCenterOffset))
+	}
+	l.OutlineCenter = center
+}
+
+// WallPoints returns the wall feet of one wall in outline order.
+func (l *Layout) WallPoints(wall Wall) []Vec3 {
+	var points []Vec3
+	for _, point := range l.Outline {
+		if point.Wall == wall {
+			points = append(points, point.Base)
+		}
+	}
+	return points
+}
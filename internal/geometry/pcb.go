@@ -0,0 +1,94 @@
+package geometry
+
+// PCB — плата под клавишей и сокет под ней, в системе координат клавиши.
+type PCB struct {
+	Board  Box
+	Socket Box
+}
+
+// NewPCB places a board of the given size with its top standoff below the
+// bottom of the keywell plane. center and socketCenter are XY positions
+// relative to the switch center, socket is the socket size below the board.
+func NewPCB(planeThickness float64, standoff float64, center Vec3, size Vec3, socketCenter Vec3, socket Vec3) *PCB {
+	top := -planeThickness - standoff
+	return &PCB{
+		Board: Box{
+			Transform: Translate(Vec3{center[0], center[1], top - size[2]/2}),
+			Size:      size,
+		},
+		Socket: Box{
+			Transform: Translate(Vec3{socketCenter[0], socketCenter[1], top - size[2] - socket[2]/2}),
+			Size:      socket,
+		},
+	}
+}
+
+type pcbPart struct {
+	ref  KeyRef
+	name string
+	box  Box
+}
+
+// pcbParts returns the boards and sockets of every key with a PCB in desk
+// coordinates.
+func (l *Layout) pcbParts() []pcbPart {
+	p := l.Params
+	var parts []pcbPart
+	add := func(ref KeyRef, transform Mat4, pcb *PCB) {
+		if pcb == nil {
+			return
+		}
+		transform = l.Base.Mul(transform)
+		parts = append(parts,
+			pcbPart{ref, "pcb", Box{Transform: transform.Mul(pcb.Board.Transform), Size: pcb.Board.Size}},
+			pcbPart{ref, "socket", Box{Transform: transform.Mul(pcb.Socket.Transform), Size: pcb.Socket.Size}},
+		)
+	}
+	for col := range p.Columns {
+		for row := range p.Rows {
+			if p.HasKey(col, row) {
+				add(KeyRef{Column: col, Row: row}, l.Keys[col][row], p.Keys[col][row].PCB)
+			}
+		}
+	}
+	for i, extra := range p.Extra {
+		add(KeyRef{Extra: true, Slot: i}, l.Extra[i], extra.PCB)
+	}
+	for i, key := range p.Thumb.Keys {
+		add(KeyRef{Thumb: true, Slot: i}, l.ThumbKeys[i], key.PCB)
+	}
+	return parts
+}
+
+// pcbInterferences tests the PCBs and sockets of different keys against each
+// other and against the switches and keywell supports of other keys. A key is
+// never tested against itself, its switch goes through its own PCB.
+func (l *Layout) pcbInterferences() []Interference {
+	parts := l.pcbParts()
+	if len(parts) == 0 {
+		return nil
+	}
+	var found []Interference
+	for i, part := range parts {
+		for _, other := range parts[i+1:] {
+			if other.ref == part.ref {
+				continue
+			}
+			if depth := Penetration(part.box, other.box); depth > collisionEpsilon {
+				found = append(found, Interference{Part: part.name + " of " + part.ref.String(), Key: other.ref, Obstacle: other.name, Depth: depth})
+			}
+		}
+	}
+	obstacles := l.keyObstacles()
+	for _, part := range parts {
+		for _, obstacle := range obstacles {
+			if obstacle.ref == part.ref {
+				continue
+			}
+			if depth := Penetration(part.box, obstacle.box); depth > collisionEpsilon {
+				found = append(found, Interference{Part: part.name + " of " + part.ref.String(), Key: obstacle.ref, Obstacle: obstacle.obstacle, Depth: depth})
+			}
+		}
+	}
+	return found
+}
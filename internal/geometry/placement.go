@@ -0,0 +1,68 @@
+package geometry
+
+// PlacedKey — клавиша в координатах стола, после M_base.
+type PlacedKey struct {
+	Key        KeyRef
+	SwitchType string
+	// Transform places the switch center on top of the keywell plane.
+	Transform Mat4
+	// Keycap is zero when the keycap size is unknown.
+	Keycap Vec3
+}
+
+// MirrorY is the mirror the right half is built with, mirror([0, 1, 0]) in
+// mirror_if_right.
+var MirrorY = Scale(Vec3{1, -1, 1})
+
+// PlacedKeys returns every keywell, extra and thumb key in desk coordinates.
+// With mirror the keys are placed as in the right half: both the position and
+// the key frame are mirrored, so the frame stays right handed.
+func (l *Layout) PlacedKeys(mirror bool) []PlacedKey {
+	var keys []PlacedKey
+	add := func(ref KeyRef, switchType string, transform Mat4, keycap Vec3) {
+		transform = l.Base.Mul(transform)
+		if mirror {
+			transform = MirrorY.Mul(transform).Mul(MirrorY)
+		}
+		keys = append(keys, PlacedKey{Key: ref, SwitchType: switchType, Transform: transform, Keycap: keycap})
+	}
+	for col := range l.Params.Columns {
+		for row := range l.Params.Rows {
+			if l.Params.HasKey(col, row) {
+				key := l.Params.Keys[col][row]
+				add(KeyRef{Column: col, Row: row}, key.SwitchType, l.Keys[col][row], key.Keycap)
+			}
+		}
+	}
+	for i, extra := range l.Params.Extra {
+		add(KeyRef{Extra: true, Slot: i}, extra.SwitchType, l.Extra[i], extra.Keycap)
+	}
+	for i, key := range l.Params.Thumb.Keys {
+		add(KeyRef{Thumb: true, Slot: i}, key.SwitchType, l.ThumbKeys[i], key.Keycap)
+	}
+	return keys
+}
+
+// Normal is the key axis pointing away from the keywell surface.
+func (k PlacedKey) Normal() Vec3 {
+	return k.Transform.Axis(2)
+}
+
+// KeycapCorners returns the corners of the keycap top in desk coordinates,
+// in the order of Params.KeyCorner: top-left, top-right, bottom-left,
+// bottom-right in key coordinates.
+func (k PlacedKey) KeycapCorners() [4]Vec3 {
+	var corners [4]Vec3
+	for corner := range corners {
+		x := k.Keycap[0] / 2
+		y := k.Keycap[1] / 2
+		if corner%2 == 0 {
+			x = -x
+		}
+		if corner >= 2 {
+			y = -y
+		}
+		corners[corner] = k.Transform.Apply(Vec3{x, y, k.Keycap[2]})
+	}
+	return corners
+}
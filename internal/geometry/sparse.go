@@ -0,0 +1,126 @@
+package geometry
+
+// Side — сторона соседней клавиши, к которой пристыкована дополнительная.
+type Side string
+
+const (
+	// SideInner is towards column 0, along +Y.
+	SideInner Side = "inner"
+	// SideOuter is away from column 0, along -Y.
+	SideOuter Side = "outer"
+	// SideBack is towards row 0 and the back wall, along -X.
+	SideBack Side = "back"
+	// SideFront is away from row 0, along +X.
+	SideFront Side = "front"
+)
+
+// ExtraKey — клавиша вне матрицы, пристыкованная к клавише матрицы.
+type ExtraKey struct {
+	// Column and Row address the neighbouring matrix key.
+	Column     int
+	Row        int
+	Side       Side
+	SwitchType string
+	Offset     Vec3
+	Rotation   Vec3
+	Keycap     Vec3
+	Body       Vec3
+	PCB        *PCB
+}
+
+// BridgeCorners returns the corners of the neighbour and of the extra key
+// that face each other, see Params.KeyCorner for the corner order.
+func (s Side) BridgeCorners() (neighbour [2]int, key [2]int) {
+	switch s {
+	case SideInner:
+		return [2]int{0, 1}, [2]int{2, 3}
+	case SideOuter:
+		return [2]int{2, 3}, [2]int{0, 1}
+	case SideBack:
+		return [2]int{0, 2}, [2]int{1, 3}
+	default:
+		return [2]int{1, 3}, [2]int{0, 2}
+	}
+}
+
+func (p Params) sideStep(side Side) Vec3 {
+	switch side {
+	case SideInner:
+		return Vec3{0, p.ColumnPitch, 0}
+	case SideOuter:
+		return Vec3{0, -p.ColumnPitch, 0}
+	case SideBack:
+		return Vec3{-p.RowPitch, 0, 0}
+	default:
+		return Vec3{p.RowPitch, 0, 0}
+	}
+}
+
+// HasKey reports whether the matrix has a key at the given column and row.
+func (p Params) HasKey(col int, row int) bool {
+	return col >= 0 && col < p.Columns && row >= 0 && row < p.Rows && p.Present[col][row]
+}
+
+// EdgeKey — клавиша на краю keywell, вдоль которой идут губы и стенки.
+type EdgeKey struct {
+	Ref       KeyRef
+	Transform Mat4
+	Keycap    Vec3
+	// Splay is the splay angle of the key column.
+	Splay float64
+}
+
+func (l *Layout) matrixEdgeKey(col int, row int) EdgeKey {
+	return EdgeKey{
+		Ref:       KeyRef{Column: col, Row: row},
+		Transform: l.Keys[col][row],
+		Keycap:    l.Params.Keys[col][row].Keycap,
+		Splay:     l.Params.columnSplay(col),
+	}
+}
+
+// attachedExtraKey returns the extra key attached to the given side of a
+// matrix key, extra keys on the edge replace their neighbour there.
+func (l *Layout) attachedExtraKey(edge EdgeKey, side Side) EdgeKey {
+	for i, extra := range l.Params.Extra {
+		if extra.Column == edge.Ref.Column && extra.Row == edge.Ref.Row && extra.Side == side {
+			return EdgeKey{Ref: KeyRef{Extra: true, Slot: i}, Transform: l.Extra[i], Keycap: extra.Keycap, Splay: edge.Splay}
+		}
+	}
+	return edge
+}
+
+// computeEdges finds the keys along the inner lip and outer lip, one per row
+// that has keys, and along the back wall, one per column.
+func (l *Layout) computeEdges() {
+	p := l.Params
+	l.InnerEdge, l.OuterEdge, l.BackEdge, l.FrontEdge = nil, nil, nil, nil
+	for row := range p.Rows {
+		for col := range p.Columns {
+			if p.HasKey(col, row) {
+				l.InnerEdge = append(l.InnerEdge, l.attachedExtraKey(l.matrixEdgeKey(col, row), SideInner))
+				break
+			}
+		}
+		for col := p.Columns - 1; col >= 0; col-- {
+			if p.HasKey(col, row) {
+				l.OuterEdge = append(l.OuterEdge, l.attachedExtraKey(l.matrixEdgeKey(col, row), SideOuter))
+				break
+			}
+		}
+	}
+	for col := range p.Columns {
+		for row := range p.Rows {
+			if p.HasKey(col, row) {
+				l.BackEdge = append(l.BackEdge, l.attachedExtraKey(l.matrixEdgeKey(col, row), SideBack))
+				break
+			}
+		}
+		for row := p.Rows - 1; row >= 0; row-- {
+			if p.HasKey(col, row) {
+				l.FrontEdge = append(l.FrontEdge, l.attachedExtraKey(l.matrixEdgeKey(col, row), SideFront))
+				break
+			}
+		}
+	}
+}
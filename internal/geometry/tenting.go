@@ -0,0 +1,109 @@
+package geometry
+
+import (
+	"math"
+	"slices"
+)
+
+// TentPivot — линия, вокруг которой наклоняется половина.
+type TentPivot string
+
+const (
+	// TentPivotCenter tents around the middle of the keywell along X.
+	TentPivotCenter TentPivot = "center"
+	// TentPivotBack tents around the back edge, the one at row 0.
+	TentPivotBack TentPivot = "back"
+	// TentPivotFront tents around the front edge, the thumb cluster side.
+	TentPivotFront TentPivot = "front"
+)
+
+// Tenting — наклон половины вокруг оси Y поверх наклона keywell.
+type Tenting struct {
+	// Angle rotates around the Y axis, positive raises the back edge.
+	Angle float64
+	Pivot TentPivot
+}
+
+type footprint struct {
+	transform Mat4
+	size      Vec3
+}
+
+// footprints returns every existing key of the half with its keycap size.
+func (l *Layout) footprints() []footprint {
+	var keys []footprint
+	for col := range l.Params.Columns {
+		for row := range l.Params.Rows {
+			if l.Params.HasKey(col, row) {
+				keys = append(keys, footprint{l.Keys[col][row], l.Params.Keys[col][row].Keycap})
+			}
+		}
+	}
+	for i, extra := range l.Params.Extra {
+		keys = append(keys, footprint{l.Extra[i], extra.Keycap})
+	}
+	for i, thumbKey := range l.Params.Thumb.Keys {
+		keys = append(keys, footprint{l.ThumbKeys[i], thumbKey.Keycap})
+	}
+	return keys
+}
+
+// lowKeys returns the keys that can end up lowest: the front and back edge,
+// which the tent lowers, the extra keys and the thumb cluster.
+func (l *Layout) lowKeys() []footprint {
+	var keys []footprint
+	for _, edge := range append(slices.Clone(l.FrontEdge), l.BackEdge...) {
+		keys = append(keys, footprint{edge.Transform, edge.Keycap})
+	}
+	for i, extra := range l.Params.Extra {
+		keys = append(keys, footprint{l.Extra[i], extra.Keycap})
+	}
+	for i, thumbKey := range l.Params.Thumb.Keys {
+		keys = append(keys, footprint{l.ThumbKeys[i], thumbKey.Keycap})
+	}
+	return keys
+}
+
+// tentTransform rotates the tilted half around a line parallel to Y through
+// the pivot edge. Only X of the pivot matters, the height is corrected by
+// M_base afterwards.
+func (l *Layout) tentTransform(tilt Mat4) Mat4 {
+	tenting := l.Params.Tenting
+	if tenting.Angle == 0 {
+		return Identity()
+	}
+	back, front := math.Inf(1), math.Inf(-1)
+	for _, key := range l.footprints() {
+		for corner := range 4 {
+			point := tilt.Mul(key.transform).Mul(l.Params.KeyCorner(key.size, corner)).Translation()
+			back = min(back, point[0])
+			front = max(front, point[0])
+		}
+	}
+	var pivot Vec3
+	switch tenting.Pivot {
+	case TentPivotBack:
+		pivot[0] = back
+	case TentPivotFront:
+		pivot[0] = front
+	default:
+		pivot[0] = (back + front) / 2
+	}
+	// My lowers +X for positive angles, which lifts the back edge
+	return Translate(pivot).Mul(My(tenting.Angle)).Mul(Translate(pivot.Scale(-1)))
+}
+
+// computeFrontWall picks the front keys outside of the thumb cluster. The
+// front edge is open when the half is flat, a tent that lifts it needs a
+// wall down to the desk there.
+func (l *Layout) computeFrontWall() {
+	l.FrontWall = nil
+	front := l.BaseTilt.ApplyVector(Vec3{1, 0, 0})
+	if front[2] <= 1e-9 {
+		return
+	}
+	// from the outer column towards the cluster, following the outline
+	for col := len(l.FrontEdge) - 1; col > l.Params.Thumb.OriginColumn; col-- {
+		l.FrontWall = append(l.FrontWall, l.FrontEdge[col])
+	}
+}
@@ -0,0 +1,124 @@
+package geometry
+
+import "math"
+
+// Trackpoint — трекпойнт между четырьмя клавишами keywell.
+type Trackpoint struct {
+	// Column and Row select the keys the trackpoint is placed between:
+	// Column and Column+1, Row and Row+1.
+	Column int
+	Row    int
+	// Offset moves the trackpoint in its own frame.
+	Offset        Vec3
+	HoleDiameter  float64
+	MountDiameter float64
+	MountDepth    float64
+	// Clearance is kept between the stem hole and the keycap cutouts.
+	Clearance float64
+}
+
+// TrackpointMount — рассчитанное место трекпойнта одной половины.
+type TrackpointMount struct {
+	Trackpoint *Trackpoint
+	// Transform has its origin on top of the keywell plane and Z along the
+	// stem.
+	Transform Mat4
+	// Cutouts holds the shrunk keycap cutouts of the keys around the stem.
+	Cutouts []KeycapCutout
+}
+
+// KeycapCutout — уменьшенный вырез под колпачок клавиши рядом со стиком.
+type KeycapCutout struct {
+	Key  KeyRef
+	Size Vec3
+}
+
+// trackpointTransform places the trackpoint in the middle of its four keys.
+// The frame averages the key axes, so it follows the keywell curvature.
+func (l *Layout) trackpointTransform(t *Trackpoint) Mat4 {
+	var position, x, z Vec3
+	for col := t.Column; col <= t.Column+1; col++ {
+		for row := t.Row; row <= t.Row+1; row++ {
+			key := l.Keys[col][row]
+			position = position.Add(key.Translation().Scale(0.25))
+			x = x.Add(key.Axis(0))
+			z = z.Add(key.Axis(2))
+		}
+	}
+	z = z.Normalize()
+	x = x.Sub(z.Scale(x.Dot(z))).Normalize()
+	y := z.Cross(x)
+	frame := Mat4{
+		{x[0], y[0], z[0], position[0]},
+		{x[1], y[1], z[1], position[1]},
+		{x[2], y[2], z[2], position[2]},
+		{0, 0, 0, 1},
+	}
+	return frame.Mul(Translate(t.Offset))
+}
+
+// shrinkKeycap returns the keycap size reduced along one axis so that it
+// keeps clear of a circle of the given radius around center, both in key
+// coordinates. The cutout stays centered on the key, the axis that loses
+// less is chosen. ok is false when the keycap does not reach the circle.
+func shrinkKeycap(size Vec3, center Vec3, radius float64) (Vec3, bool) {
+	dx := max(math.Abs(center[0])-size[0]/2, 0)
+	dy := max(math.Abs(center[1])-size[1]/2, 0)
+	if dx*dx+dy*dy >= radius*radius {
+		return size, false
+	}
+	alongX := size
+	alongX[0] = max(2*(math.Abs(center[0])-radius), 0)
+	alongY := size
+	alongY[1] = max(2*(math.Abs(center[1])-radius), 0)
+	if size[0]-alongX[0] <= size[1]-alongY[1] {
+		return alongX, true
+	}
+	return alongY, true
+}
+
+// computeTrackpoint places the trackpoint and shrinks the keycap cutouts that
+// reach its stem hole.
+func (l *Layout) computeTrackpoint() {
+	t := l.Params.Trackpoint
+	if t == nil {
+		return
+	}
+	mount := &TrackpointMount{Trackpoint: t, Transform: l.trackpointTransform(t)}
+	stem := mount.Transform.Translation()
+	radius := t.HoleDiameter/2 + t.Clearance
+	for _, key := range l.keycaps() {
+		// the keycap box only differs from the key transform along its Z
+		local := stem.Sub(key.box.Transform.Translation())
+		center := Vec3{local.Dot(key.box.Transform.Axis(0)), local.Dot(key.box.Transform.Axis(1)), 0}
+		if size, ok := shrinkKeycap(key.box.Size, center, radius); ok {
+			mount.Cutouts = append(mount.Cutouts, KeycapCutout{Key: key.ref, Size: size})
+		}
+	}
+	l.Trackpoint = mount
+}
+
+// trackpointMountBox is the mount boss under the keywell plane in desk
+// coordinates.
+func (l *Layout) trackpointMountBox() Box {
+	t := l.Trackpoint.Trackpoint
+	return Box{
+		Transform: l.Base.Mul(l.Trackpoint.Transform).Mul(Translate(Vec3{0, 0, -l.Params.PlaneThickness - t.MountDepth/2})),
+		Size:      Vec3{t.MountDiameter, t.MountDiameter, t.MountDepth},
+	}
+}
+
+// trackpointInterferences tests the mount boss against the switches. The boss
+// grows out of the keywell plane, so the supports are not obstacles.
+func (l *Layout) trackpointInterferences() []Interference {
+	if l.Trackpoint == nil || l.Trackpoint.Trackpoint.MountDepth <= 0 {
+		return nil
+	}
+	var found []Interference
+	for _, interference := range l.interferences("trackpoint mount", l.trackpointMountBox()) {
+		if interference.Obstacle == "switch" {
+			found = append(found, interference)
+		}
+	}
+	return found
+}
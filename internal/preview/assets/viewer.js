@@ -0,0 +1,525 @@
+"use strict";
+
+// Preview page of typemon serve. The server pushes its state over
+// Server-Sent Events, rendered STL files are fetched when their URL changes.
+// Without rendered models the key layout is drawn as keycap boxes. Z is up,
+// as in OpenSCAD.
+
+const canvas = document.getElementById("view");
+const statusLine = document.getElementById("status");
+const partsList = document.getElementById("parts");
+const errorsBox = document.getElementById("errors");
+const gl = canvas.getContext("webgl");
+
+// gap between the halves along Y when their models would overlap
+const HALF_GAP = 10;
+// keycap drawn for keys without a known keycap size
+const DEFAULT_KEYCAP = [18, 18, 4];
+const KEY_LAYOUT = "key layout";
+
+const COLORS = {
+  "left": [0.56, 0.70, 0.85],
+  "right": [0.85, 0.65, 0.56],
+  "left bottom": [0.40, 0.50, 0.62],
+  "right bottom": [0.62, 0.47, 0.40],
+  "left keys": [0.80, 0.80, 0.80],
+  "right keys": [0.80, 0.80, 0.80],
+};
+
+const VERTEX_SHADER = `
+attribute vec3 position;
+attribute vec3 normal;
+uniform mat4 viewProjection;
+uniform vec3 offset;
+varying vec3 vNormal;
+void main() {
+  vNormal = normal;
+  gl_Position = viewProjection * vec4(position + offset, 1.0);
+}`;
+
+const FRAGMENT_SHADER = `
+precision mediump float;
+uniform vec3 color;
+uniform vec3 light;
+varying vec3 vNormal;
+void main() {
+  // mirrored halves flip the winding, so both sides are lit
+  float diffuse = abs(dot(normalize(vNormal), light));
+  gl_FragColor = vec4(color * (0.35 + 0.65 * diffuse), 1.0);
+}`;
+
+// meshes by name, every mesh belongs to a half and is drawn with its offset
+const meshes = new Map();
+// URL of the loaded file of every model
+const loaded = new Map();
+const visible = new Map();
+let keysJSON = "";
+let fitted = false;
+
+const camera = { yaw: Math.PI + 0.6, pitch: 0.7, distance: 400, target: [0, 0, 0] };
+
+// ---------------------------------------------------------------------------
+// math, column major matrices as WebGL expects them
+
+function multiply(a, b) {
+  const out = new Float32Array(16);
+  for (let col = 0; col < 4; col++) {
+    for (let row = 0; row < 4; row++) {
+      let sum = 0;
+      for (let k = 0; k < 4; k++) {
+        sum += a[k * 4 + row] * b[col * 4 + k];
+      }
+      out[col * 4 + row] = sum;
+    }
+  }
+  return out;
+}
+
+function perspective(fovy, aspect, near, far) {
+  const f = 1 / Math.tan(fovy / 2);
+  const out = new Float32Array(16);
+  out[0] = f / aspect;
+  out[5] = f;
+  out[10] = (far + near) / (near - far);
+  out[11] = -1;
+  out[14] = (2 * far * near) / (near - far);
+  return out;
+}
+
+function sub(a, b) {
+  return [a[0] - b[0], a[1] - b[1], a[2] - b[2]];
+}
+
+function cross(a, b) {
+  return [a[1] * b[2] - a[2] * b[1], a[2] * b[0] - a[0] * b[2], a[0] * b[1] - a[1] * b[0]];
+}
+
+function dot(a, b) {
+  return a[0] * b[0] + a[1] * b[1] + a[2] * b[2];
+}
+
+function normalize(v) {
+  const length = Math.hypot(v[0], v[1], v[2]) || 1;
+  return [v[0] / length, v[1] / length, v[2] / length];
+}
+
+function lookAt(eye, target, up) {
+  const z = normalize(sub(eye, target));
+  const x = normalize(cross(up, z));
+  const y = cross(z, x);
+  return new Float32Array([
+    x[0], y[0], z[0], 0,
+    x[1], y[1], z[1], 0,
+    x[2], y[2], z[2], 0,
+    -dot(x, eye), -dot(y, eye), -dot(z, eye), 1,
+  ]);
+}
+
+function cameraEye() {
+  const c = Math.cos(camera.pitch);
+  return [
+    camera.target[0] + camera.distance * c * Math.cos(camera.yaw),
+    camera.target[1] + camera.distance * c * Math.sin(camera.yaw),
+    camera.target[2] + camera.distance * Math.sin(camera.pitch),
+  ];
+}
+
+// ---------------------------------------------------------------------------
+// geometry
+
+// parseSTL returns the triangle vertices of a binary or ASCII STL file.
+function parseSTL(buffer) {
+  const view = new DataView(buffer);
+  if (buffer.byteLength >= 84) {
+    const count = view.getUint32(80, true);
+    if (84 + count * 50 === buffer.byteLength) {
+      const positions = new Float32Array(count * 9);
+      for (let i = 0; i < count; i++) {
+        // skip the stored normal, it is recomputed
+        const base = 84 + i * 50 + 12;
+        for (let j = 0; j < 9; j++) {
+          positions[i * 9 + j] = view.getFloat32(base + j * 4, true);
+        }
+      }
+      return positions;
+    }
+  }
+  const text = new TextDecoder().decode(buffer);
+  const vertex = /vertex\s+(\S+)\s+(\S+)\s+(\S+)/g;
+  const values = [];
+  let match;
+  while ((match = vertex.exec(text)) !== null) {
+    values.push(Number(match[1]), Number(match[2]), Number(match[3]));
+  }
+  return new Float32Array(values);
+}
+
+function faceNormals(positions) {
+  const normals = new Float32Array(positions.length);
+  for (let i = 0; i < positions.length; i += 9) {
+    const a = [positions[i], positions[i + 1], positions[i + 2]];
+    const b = [positions[i + 3], positions[i + 4], positions[i + 5]];
+    const c = [positions[i + 6], positions[i + 7], positions[i + 8]];
+    const n = normalize(cross(sub(b, a), sub(c, a)));
+    for (let j = 0; j < 3; j++) {
+      normals.set(n, i + j * 3);
+    }
+  }
+  return normals;
+}
+
+// keyTriangles builds the keycap box of every key, from the top of the
+// keywell plane up to the keycap depth.
+function keyTriangles(keys) {
+  const faces = [
+    [0, 1, 3, 2], [4, 6, 7, 5], [0, 4, 5, 1],
+    [2, 3, 7, 6], [0, 2, 6, 4], [1, 5, 7, 3],
+  ];
+  const values = [];
+  for (const key of keys) {
+    const size = key.keycap[0] > 0 ? key.keycap : DEFAULT_KEYCAP;
+    const m = key.transform;
+    const corners = [];
+    for (let i = 0; i < 8; i++) {
+      const x = (i & 4 ? 0.5 : -0.5) * size[0];
+      const y = (i & 2 ? 0.5 : -0.5) * size[1];
+      const z = (i & 1 ? 1 : 0) * size[2];
+      corners.push([
+        m[0] * x + m[4] * y + m[8] * z + m[12],
+        m[1] * x + m[5] * y + m[9] * z + m[13],
+        m[2] * x + m[6] * y + m[10] * z + m[14],
+      ]);
+    }
+    for (const face of faces) {
+      for (const i of [face[0], face[1], face[2], face[0], face[2], face[3]]) {
+        values.push(...corners[i]);
+      }
+    }
+  }
+  return new Float32Array(values);
+}
+
+function bounds(positions) {
+  const min = [Infinity, Infinity, Infinity];
+  const max = [-Infinity, -Infinity, -Infinity];
+  for (let i = 0; i < positions.length; i += 3) {
+    for (let j = 0; j < 3; j++) {
+      min[j] = Math.min(min[j], positions[i + j]);
+      max[j] = Math.max(max[j], positions[i + j]);
+    }
+  }
+  return { min, max };
+}
+
+// ---------------------------------------------------------------------------
+// WebGL
+
+function compile(type, source) {
+  const shader = gl.createShader(type);
+  gl.shaderSource(shader, source);
+  gl.compileShader(shader);
+  if (!gl.getShaderParameter(shader, gl.COMPILE_STATUS)) {
+    throw new Error(gl.getShaderInfoLog(shader));
+  }
+  return shader;
+}
+
+function createProgram() {
+  const program = gl.createProgram();
+  gl.attachShader(program, compile(gl.VERTEX_SHADER, VERTEX_SHADER));
+  gl.attachShader(program, compile(gl.FRAGMENT_SHADER, FRAGMENT_SHADER));
+  gl.linkProgram(program);
+  if (!gl.getProgramParameter(program, gl.LINK_STATUS)) {
+    throw new Error(gl.getProgramInfoLog(program));
+  }
+  return {
+    program,
+    position: gl.getAttribLocation(program, "position"),
+    normal: gl.getAttribLocation(program, "normal"),
+    viewProjection: gl.getUniformLocation(program, "viewProjection"),
+    offset: gl.getUniformLocation(program, "offset"),
+    color: gl.getUniformLocation(program, "color"),
+    light: gl.getUniformLocation(program, "light"),
+  };
+}
+
+function setMesh(name, half, positions) {
+  const previous = meshes.get(name);
+  if (previous) {
+    gl.deleteBuffer(previous.positions);
+    gl.deleteBuffer(previous.normals);
+  }
+  if (positions.length === 0) {
+    meshes.delete(name);
+    return;
+  }
+  const positionBuffer = gl.createBuffer();
+  gl.bindBuffer(gl.ARRAY_BUFFER, positionBuffer);
+  gl.bufferData(gl.ARRAY_BUFFER, positions, gl.STATIC_DRAW);
+  const normalBuffer = gl.createBuffer();
+  gl.bindBuffer(gl.ARRAY_BUFFER, normalBuffer);
+  gl.bufferData(gl.ARRAY_BUFFER, faceNormals(positions), gl.STATIC_DRAW);
+  meshes.set(name, {
+    half,
+    group: name.endsWith("keys") ? KEY_LAYOUT : name,
+    positions: positionBuffer,
+    normals: normalBuffer,
+    count: positions.length / 3,
+    bounds: bounds(positions),
+    offset: [0, 0, 0],
+  });
+}
+
+function hasModels() {
+  return [...meshes.values()].some((mesh) => mesh.group !== KEY_LAYOUT);
+}
+
+// groupVisible keeps the choice of the user, the key layout is only shown by
+// default while there are no rendered models.
+function groupVisible(group) {
+  if (visible.has(group)) {
+    return visible.get(group);
+  }
+  return group !== KEY_LAYOUT || !hasModels();
+}
+
+function isVisible(mesh) {
+  return groupVisible(mesh.group);
+}
+
+// placeHalves moves the halves apart along Y when they overlap. The right
+// half is mirrored across Y, so the left half goes to +Y.
+function placeHalves() {
+  const extent = { left: Infinity, right: -Infinity };
+  for (const mesh of meshes.values()) {
+    if (!isVisible(mesh)) {
+      continue;
+    }
+    if (mesh.half === "left") {
+      extent.left = Math.min(extent.left, mesh.bounds.min[1]);
+    } else {
+      extent.right = Math.max(extent.right, mesh.bounds.max[1]);
+    }
+  }
+  const shift = {
+    left: Number.isFinite(extent.left) ? Math.max(0, HALF_GAP / 2 - extent.left) : 0,
+    right: Number.isFinite(extent.right) ? Math.min(0, -HALF_GAP / 2 - extent.right) : 0,
+  };
+  for (const mesh of meshes.values()) {
+    mesh.offset = [0, shift[mesh.half] || 0, 0];
+  }
+}
+
+function fit() {
+  const min = [Infinity, Infinity, Infinity];
+  const max = [-Infinity, -Infinity, -Infinity];
+  for (const mesh of meshes.values()) {
+    if (!isVisible(mesh)) {
+      continue;
+    }
+    for (let j = 0; j < 3; j++) {
+      min[j] = Math.min(min[j], mesh.bounds.min[j] + mesh.offset[j]);
+      max[j] = Math.max(max[j], mesh.bounds.max[j] + mesh.offset[j]);
+    }
+  }
+  if (!Number.isFinite(min[0])) {
+    return false;
+  }
+  camera.target = [(min[0] + max[0]) / 2, (min[1] + max[1]) / 2, (min[2] + max[2]) / 2];
+  const radius = Math.hypot(max[0] - min[0], max[1] - min[1], max[2] - min[2]) / 2;
+  camera.distance = Math.max(radius / Math.sin(Math.PI / 8), 10) * 1.1;
+  return true;
+}
+
+let program = null;
+let drawQueued = false;
+
+function requestDraw() {
+  if (!drawQueued) {
+    drawQueued = true;
+    requestAnimationFrame(draw);
+  }
+}
+
+function draw() {
+  drawQueued = false;
+  const width = canvas.clientWidth * devicePixelRatio;
+  const height = canvas.clientHeight * devicePixelRatio;
+  if (canvas.width !== width || canvas.height !== height) {
+    canvas.width = width;
+    canvas.height = height;
+  }
+  gl.viewport(0, 0, canvas.width, canvas.height);
+  gl.clearColor(0.118, 0.122, 0.133, 1);
+  gl.clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT);
+
+  const eye = cameraEye();
+  const near = camera.distance / 100;
+  const projection = perspective(Math.PI / 4, canvas.width / Math.max(canvas.height, 1), near, camera.distance * 10);
+  const view = lookAt(eye, camera.target, [0, 0, 1]);
+  gl.useProgram(program.program);
+  gl.uniformMatrix4fv(program.viewProjection, false, multiply(projection, view));
+  gl.uniform3fv(program.light, normalize(sub(eye, camera.target)));
+  for (const [name, mesh] of meshes) {
+    if (!isVisible(mesh)) {
+      continue;
+    }
+    gl.uniform3fv(program.offset, mesh.offset);
+    gl.uniform3fv(program.color, COLORS[name] || [0.7, 0.7, 0.7]);
+    gl.bindBuffer(gl.ARRAY_BUFFER, mesh.positions);
+    gl.enableVertexAttribArray(program.position);
+    gl.vertexAttribPointer(program.position, 3, gl.FLOAT, false, 0, 0);
+    gl.bindBuffer(gl.ARRAY_BUFFER, mesh.normals);
+    gl.enableVertexAttribArray(program.normal);
+    gl.vertexAttribPointer(program.normal, 3, gl.FLOAT, false, 0, 0);
+    gl.drawArrays(gl.TRIANGLES, 0, mesh.count);
+  }
+}
+
+// ---------------------------------------------------------------------------
+// state from the server
+
+function updateParts() {
+  const groups = [];
+  for (const mesh of meshes.values()) {
+    if (!groups.includes(mesh.group)) {
+      groups.push(mesh.group);
+    }
+  }
+  groups.sort();
+  partsList.replaceChildren(...groups.map((group) => {
+    const label = document.createElement("label");
+    const checkbox = document.createElement("input");
+    checkbox.type = "checkbox";
+    checkbox.checked = groupVisible(group);
+    checkbox.addEventListener("change", () => {
+      visible.set(group, checkbox.checked);
+      placeHalves();
+      requestDraw();
+    });
+    label.append(checkbox, " " + group);
+    return label;
+  }));
+}
+
+function sceneChanged() {
+  placeHalves();
+  if (!fitted) {
+    fitted = fit();
+  }
+  updateParts();
+  requestDraw();
+}
+
+async function loadModel(model) {
+  try {
+    const response = await fetch(model.url);
+    if (!response.ok) {
+      throw new Error(response.status + " " + response.statusText);
+    }
+    const positions = parseSTL(await response.arrayBuffer());
+    // a newer render may have arrived meanwhile
+    if (loaded.get(model.name) !== model.url) {
+      return;
+    }
+    setMesh(model.name, model.half, positions);
+    sceneChanged();
+  } catch (err) {
+    showErrors(["failed to load " + model.name + ": " + err.message]);
+  }
+}
+
+function showErrors(errors) {
+  errorsBox.hidden = errors.length === 0;
+  errorsBox.textContent = errors.join("\n\n");
+}
+
+function applyState(state) {
+  let status = state.status;
+  if (!state.render) {
+    status += ", OpenSCAD not found, showing the key layout";
+  }
+  statusLine.textContent = status;
+  showErrors(state.errors || []);
+
+  const keys = JSON.stringify(state.keys || []);
+  if (keys !== keysJSON) {
+    keysJSON = keys;
+    for (const half of ["left", "right"]) {
+      setMesh(half + " keys", half, keyTriangles((state.keys || []).filter((key) => key.half === half)));
+    }
+    sceneChanged();
+  }
+  for (const model of state.models || []) {
+    if (loaded.get(model.name) !== model.url) {
+      loaded.set(model.name, model.url);
+      loadModel(model);
+    }
+  }
+}
+
+function connect() {
+  const events = new EventSource("/events");
+  events.addEventListener("state", (event) => applyState(JSON.parse(event.data)));
+  events.onerror = () => {
+    statusLine.textContent = "disconnected, retrying";
+  };
+}
+
+// ---------------------------------------------------------------------------
+// controls: drag to orbit, right or shift drag to pan, wheel to zoom and
+// double click to fit
+
+function setupControls() {
+  let last = null;
+  canvas.addEventListener("contextmenu", (event) => event.preventDefault());
+  canvas.addEventListener("pointerdown", (event) => {
+    canvas.setPointerCapture(event.pointerId);
+    last = { x: event.clientX, y: event.clientY, pan: event.button === 2 || event.shiftKey };
+  });
+  canvas.addEventListener("pointerup", () => {
+    last = null;
+  });
+  canvas.addEventListener("pointermove", (event) => {
+    if (!last) {
+      return;
+    }
+    const dx = event.clientX - last.x;
+    const dy = event.clientY - last.y;
+    last.x = event.clientX;
+    last.y = event.clientY;
+    if (last.pan) {
+      const forward = normalize(sub(camera.target, cameraEye()));
+      const right = normalize(cross(forward, [0, 0, 1]));
+      const up = cross(right, forward);
+      const scale = camera.distance * 0.0015;
+      for (let j = 0; j < 3; j++) {
+        camera.target[j] += (-right[j] * dx + up[j] * dy) * scale;
+      }
+    } else {
+      camera.yaw -= dx * 0.01;
+      camera.pitch = Math.max(-1.55, Math.min(1.55, camera.pitch + dy * 0.01));
+    }
+    requestDraw();
+  });
+  canvas.addEventListener("wheel", (event) => {
+    event.preventDefault();
+    camera.distance *= Math.exp(event.deltaY * 0.001);
+    requestDraw();
+  }, { passive: false });
+  canvas.addEventListener("dblclick", () => {
+    fit();
+    requestDraw();
+  });
+  window.addEventListener("resize", requestDraw);
+}
+
+if (!gl) {
+  statusLine.textContent = "WebGL is not available in this browser";
+} else {
+  program = createProgram();
+  gl.enable(gl.DEPTH_TEST);
+  setupControls();
+  connect();
+  requestDraw();
+}
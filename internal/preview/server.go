@@ -0,0 +1,243 @@
+package preview
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"typemon/internal/geometry"
+)
+
+//go:embed assets
+var assets embed.FS
+
+const (
+	StatusGenerating = "generating"
+	StatusRendering  = "rendering"
+	StatusReady      = "ready"
+)
+
+// Model — отрендеренная часть модели, которую показывает просмотрщик.
+type Model struct {
+	// Name is the render job name, e.g. "left" or "right bottom".
+	Name string `json:"name"`
+	Half string `json:"half"`
+	// URL changes with every render so the browser does not use a stale
+	// copy.
+	URL  string `json:"url"`
+	file string
+}
+
+// Key — колпачок клавиши для просмотра раскладки без OpenSCAD.
+type Key struct {
+	Half string `json:"half"`
+	Name string `json:"name"`
+	// Transform is the key transform in desk coordinates, column major as
+	// WebGL expects it.
+	Transform [16]float64 `json:"transform"`
+	Keycap    [3]float64  `json:"keycap"`
+}
+
+// NewKeys converts the placed keys of one half.
+func NewKeys(half string, placed []geometry.PlacedKey) []Key {
+	keys := make([]Key, 0, len(placed))
+	for _, key := range placed {
+		k := Key{Half: half, Name: key.Key.String(), Keycap: key.Keycap}
+		for col := range 4 {
+			for row := range 4 {
+				k.Transform[col*4+row] = key.Transform[row][col]
+			}
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// State — то, что видит страница просмотра.
+type State struct {
+	// Version grows with every update.
+	Version int    `json:"version"`
+	Status  string `json:"status"`
+	// Render is false when OpenSCAD is not available, the page shows the
+	// key layout then.
+	Render bool     `json:"render"`
+	Errors []string `json:"errors"`
+	Models []Model  `json:"models"`
+	Keys   []Key    `json:"keys"`
+}
+
+// SetModel adds the rendered file of a job or replaces its previous render.
+func (s *State) SetModel(name string, half string, file string) {
+	model := Model{
+		Name: name,
+		Half: half,
+		URL:  "/models/" + filepath.Base(file) + "?v=" + strconv.Itoa(s.Version+1),
+		file: file,
+	}
+	for i := range s.Models {
+		if s.Models[i].Name == name {
+			s.Models[i] = model
+			return
+		}
+	}
+	s.Models = append(s.Models, model)
+}
+
+// Server — локальный сервер предпросмотра. Страница получает состояние
+// через Server-Sent Events при каждом обновлении.
+type Server struct {
+	mu      sync.Mutex
+	state   State
+	clients map[chan struct{}]bool
+}
+
+func New(render bool) *Server {
+	return &Server{
+		state:   State{Status: StatusGenerating, Render: render},
+		clients: make(map[chan struct{}]bool),
+	}
+}
+
+// Update changes the state and notifies every connected page.
+func (s *Server) Update(update func(state *State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.state)
+	s.state.Version++
+	for client := range s.clients {
+		// a page that has not caught up gets the latest state anyway
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) snapshot() (int, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(s.state)
+	return s.state.Version, data, err
+}
+
+func (s *Server) subscribe() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client := make(chan struct{}, 1)
+	s.clients[client] = true
+	return client
+}
+
+func (s *Server) unsubscribe(client chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, client)
+}
+
+// modelFile returns the file of the model served under the given base name,
+// only rendered models are served.
+func (s *Server) modelFile(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, model := range s.state.Models {
+		if filepath.Base(model.file) == name {
+			return model.file, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	client := s.subscribe()
+	defer s.unsubscribe(client)
+	sent := -1
+	for {
+		version, data, err := s.snapshot()
+		if err != nil {
+			fmt.Println("preview: failed to encode state: " + err.Error())
+			return
+		}
+		// a notification may arrive for a state that was already sent
+		if version != sent {
+			_, err = fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+			sent = version
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client:
+		}
+	}
+}
+
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.modelFile(r.PathValue("name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeFile(w, r, file)
+}
+
+func (s *Server) handler() (http.Handler, error) {
+	static, err := fs.Sub(assets, "assets")
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /models/{name}", s.handleModel)
+	return mux, nil
+}
+
+// ListenAndServe serves the preview on localhost only until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, port int) error {
+	handler, err := s.handler()
+	if err != nil {
+		return errors.Join(errors.New("failed to load preview assets"), err)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return errors.Join(errors.New("failed to listen"), err)
+	}
+	server := &http.Server{
+		Handler: handler,
+		// open event streams end with ctx, so shutdown does not wait for them
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("serving preview on http://%s\n", listener.Addr())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	select {
+	case err := <-errs:
+		return errors.Join(errors.New("failed to serve preview"), err)
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdown)
+}
@@ -0,0 +1,183 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the watcher waits after the last change of a
+// burst before it reports the burst.
+const DefaultDebounce = 200 * time.Millisecond
+
+// Watcher следит за набором файлов и каталогов и сообщает об изменениях
+// пачками. Файлы отслеживаются через их каталоги, поэтому переживают
+// атомарное сохранение редактором (запись во временный файл и rename).
+type Watcher struct {
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	// files are the watched files, dirs the watched trees, any change inside
+	// them counts.
+	files map[string]bool
+	dirs  []string
+	// added are the directories registered with fsnotify.
+	added map[string]bool
+}
+
+func New(debounce time.Duration) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create watcher"), err)
+	}
+	return &Watcher{
+		watcher:  watcher,
+		debounce: debounce,
+		files:    make(map[string]bool),
+		added:    make(map[string]bool),
+	}, nil
+}
+
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
+
+// Set replaces the watched paths. Directories are watched with everything
+// inside them, files through their parent directory so that a replaced file
+// is picked up again. Missing paths are watched once they appear in an
+// existing parent directory, paths in missing directories are skipped.
+func (w *Watcher) Set(paths []string) error {
+	files := make(map[string]bool)
+	var dirs []string
+	wanted := make(map[string]bool)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+			err = filepath.WalkDir(path, func(dir string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if entry.IsDir() {
+					wanted[dir] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return errors.Join(errors.New("failed to walk watched directory: "+path), err)
+			}
+			continue
+		}
+		files[path] = true
+		wanted[filepath.Dir(path)] = true
+	}
+
+	for dir := range w.added {
+		if !wanted[dir] {
+			// the directory may be gone already, nothing to undo then
+			_ = w.watcher.Remove(dir)
+			delete(w.added, dir)
+		}
+	}
+	for dir := range wanted {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if err := w.add(dir); err != nil {
+			return err
+		}
+	}
+	w.files = files
+	w.dirs = dirs
+	return nil
+}
+
+func (w *Watcher) add(dir string) error {
+	if w.added[dir] {
+		return nil
+	}
+	if err := w.watcher.Add(dir); err != nil {
+		return errors.Join(errors.New("failed to watch directory: "+dir), err)
+	}
+	w.added[dir] = true
+	return nil
+}
+
+// inTree reports whether the path is inside one of the watched trees.
+func (w *Watcher) inTree(path string) bool {
+	return slices.ContainsFunc(w.dirs, func(dir string) bool {
+		return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+	})
+}
+
+// relevant reports whether the event changes a watched path. New directories
+// inside a watched tree are watched too.
+func (w *Watcher) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	path := filepath.Clean(event.Name)
+	if w.files[path] {
+		return true
+	}
+	if !w.inTree(path) {
+		return false
+	}
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if err := w.add(path); err != nil {
+				fmt.Println("watch: " + err.Error())
+			}
+		}
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(w.added, path)
+	}
+	return true
+}
+
+// Run waits for changes and calls onChange with the changed paths of every
+// burst, once no change came for the debounce time. It returns nil when ctx
+// is cancelled. onChange runs on the Run goroutine and may call Set.
+func (w *Watcher) Run(ctx context.Context, onChange func(changed []string)) error {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	changed := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return errors.New("watcher closed")
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			changed[filepath.Clean(event.Name)] = true
+			timer.Reset(w.debounce)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return errors.New("watcher closed")
+			}
+			fmt.Println("watch: " + err.Error())
+		case <-timer.C:
+			paths := make([]string, 0, len(changed))
+			for path := range changed {
+				paths = append(paths, path)
+			}
+			slices.Sort(paths)
+			clear(changed)
+			onChange(paths)
+		}
+	}
+}